@@ -0,0 +1,77 @@
+package xl
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// AutoFitColumn sets the width of a column to fit the cells added to it so
+// far, call it once the sheet is populated. Text metrics are not available,
+// so the width is estimated: the longest line of a cell in characters,
+// scaled by the font size relative to the default 11pt and by 1.1 for bold
+// text, plus a margin of two characters. Columns without values are left
+// unchanged.
+func (s *Sheet) AutoFitColumn(colNumber int) {
+	if colNumber <= 0 {
+		return
+	}
+	var w float64
+	for _, r := range s.Rows {
+		for _, c := range r.Cells {
+			if c.columnNumber == colNumber {
+				w = max(w, s.estimateWidth(c))
+			}
+		}
+	}
+	if w > 0 {
+		s.SetColumnWidth(colNumber, float32(min(w, MaxColumnWidth)))
+	}
+}
+
+// AutoFitColumns applies AutoFitColumn to every column that has values.
+func (s *Sheet) AutoFitColumns() {
+	widths := map[int]float64{}
+	for _, r := range s.Rows {
+		for _, c := range r.Cells {
+			widths[c.columnNumber] = max(widths[c.columnNumber], s.estimateWidth(c))
+		}
+	}
+	for n, w := range widths {
+		if w > 0 {
+			s.SetColumnWidth(n, float32(min(w, MaxColumnWidth)))
+		}
+	}
+}
+
+// estimateWidth returns the approximate width of the cell content in
+// characters of the default font, or 0 for cells without a value.
+func (s *Sheet) estimateWidth(c *Cell) float64 {
+	var n int
+	switch c.typ {
+	case CellTypeSharedString, CellTypeInlineString:
+		for _, line := range strings.Split(c.v, "\n") {
+			n = max(n, utf8.RuneCountInString(line))
+		}
+	case CellTypeNumber, CellTypeError:
+		n = len(c.v)
+	case CellTypeBool:
+		n = len("FALSE")
+	case CellTypeDate:
+		n = len("2006-01-02")
+		if c.dateTime {
+			n = len("2006-01-02 15:04")
+		}
+	default:
+		return 0
+	}
+
+	w := float64(n)
+	font := cellXF(s, c).Font
+	if font.Size > 0 {
+		w *= font.Size / 11
+	}
+	if font.Bold {
+		w *= 1.1
+	}
+	return w + 2
+}
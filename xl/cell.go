@@ -1,6 +1,12 @@
 package xl
 
-import "fmt"
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+)
 
 type Cell struct {
 	row          *Row
@@ -9,15 +15,41 @@ type Cell struct {
 	typ          CellType
 	v            string
 	picture      *PictureInfo
+	comment      *Comment
+	hyperlink    *Hyperlink
+	nonFinite    bool // the value is an error substituted for NaN or ±Inf
+	dateTime     bool // the date value has a time of day
 
 	XF
 }
 
+// PictureInfo is an image placed in a cell as a rich value ("Place in
+// Cell" in Excel). Supported formats are PNG and JPEG.
+//
+// The rich value format has no notion of display size for local images:
+// Excel always scales the picture to fit the cell while keeping its aspect
+// ratio. To control how large the picture appears, size the cell instead
+// with Row.Height and Sheet.SetColumnWidth.
 type PictureInfo struct {
 	Extension string
 	Blob      []byte
 }
 
+// Comment is a legacy cell comment (note), shown in a floating box next to
+// the cell. Box dimensions are in pixels, zero selects the default size.
+type Comment struct {
+	Author string
+	Text   string
+	Width  int
+	Height int
+}
+
+// Default dimensions of the comment box, in pixels.
+const (
+	DefaultCommentWidth  = 144
+	DefaultCommentHeight = 80
+)
+
 // CellType is the type of cell value type.
 type CellType int
 
@@ -27,24 +59,78 @@ const (
 	CellTypeBool
 	CellTypeDate
 	CellTypeError
-	CellTypeFormula
+	CellTypeFormula // reserved, cells do not hold formulas and never have this type
 	CellTypeInlineString
 	CellTypeNumber
 	CellTypeSharedString
 
 	// internal
 	cellTypePicture
+	cellTypeSharedIndex
 )
 
 type XF struct {
-	Alignment Alignment
+	NumFmt     string // number format code, e.g. "#,##0.00"; empty for General
+	Font       Font
+	Fill       Fill
+	Border     Border
+	Alignment  Alignment
+	Protection CellProtection
+
+	// Style is the name of a named style that provides the components not
+	// set above, see Workbook.AddNamedStyle.
+	Style string
+}
+
+// Font describes the text appearance of a cell, zero values select the
+// workbook defaults.
+type Font struct {
+	Bold   bool
+	Italic bool
+	Size   float64 // in points, 11 when zero
+	Color  string  // RGB as "FF0000" or "#FF0000", or ARGB; empty for automatic
+
+	// Name is the font face, e.g. "Arial"; Calibri when empty. Family is
+	// the font family used when the face is not available: 1 roman, 2
+	// swiss, 3 modern, 4 script, 5 decorative; zero omits it.
+	Name   string
+	Family int
+
+	// VertAlign raises or lowers the text, one of the VertAlign constants;
+	// empty for baseline.
+	VertAlign string
 }
 
+// Font vertical alignments.
+const (
+	VertAlignBaseline    = "baseline"
+	VertAlignSuperscript = "superscript"
+	VertAlignSubscript   = "subscript"
+)
+
 type Alignment struct {
 	Horizontal string
 	Vertical   string
+
+	// WrapText breaks long text into lines within the cell, ShrinkToFit
+	// reduces the font size until the text fits instead; they are
+	// mutually exclusive. Indent is the number of indentation steps, which
+	// applies to left, right and distributed horizontal alignments.
+	WrapText    bool
+	ShrinkToFit bool
+	Indent      int
+
+	// TextRotation is the angle of the text in degrees: 1 to 90 rotate it
+	// counterclockwise, 91 to 180 rotate it clockwise by 90 less than that
+	// (180 is -90 degrees), and TextRotationStacked stacks the characters
+	// vertically.
+	TextRotation int
 }
 
+// TextRotationStacked is the Alignment.TextRotation of vertical text with
+// the characters stacked on top of each other.
+const TextRotationStacked = 255
+
 func (c *Cell) SetBool(v bool) {
 	c.typ = CellTypeBool
 	if v {
@@ -59,9 +145,66 @@ func (c *Cell) SetInt(v int64) {
 	c.v = fmt.Sprintf("%d", v)
 }
 
-func (c *Cell) SetFloat(v float64) {
+// setUint is SetInt for unsigned values, which are written exactly even
+// beyond the range of int64.
+func (c *Cell) setUint(v uint64) {
 	c.typ = CellTypeNumber
+	c.v = strconv.FormatUint(v, 10)
+}
+
+// SetFloat stores a number. NaN and ±Inf have no representation in the
+// file format, see setNumber for how they are handled.
+func (c *Cell) SetFloat(v float64) {
+	c.setNumber(v, func(v float64) string {
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	})
+}
 
+// SetFloatPrec stores a number rounded to prec digits after the decimal
+// point, e.g. to drop the noise of binary floating point in computed
+// values. The rounded value is what Excel sees; to keep the full value and
+// only change how it is displayed, set a number format instead. A negative
+// prec is treated as 0.
+func (c *Cell) SetFloatPrec(v float64, prec int) {
+	prec = max(prec, 0)
+	c.setNumber(v, func(v float64) string {
+		return strconv.FormatFloat(v, 'f', prec, 64)
+	})
+}
+
+// setNumber is the common path of all floating point setters. Non-finite
+// values are stored as error values instead: NaN becomes #NUM! and ±Inf
+// becomes #DIV/0!. Set Writer.RejectNonFinite to fail the write instead.
+func (c *Cell) setNumber(v float64, format func(v float64) string) {
+	c.nonFinite = false
+	switch {
+	case math.IsNaN(v):
+		c.typ = CellTypeError
+		c.v = "#NUM!"
+		c.nonFinite = true
+	case math.IsInf(v, 0):
+		c.typ = CellTypeError
+		c.v = "#DIV/0!"
+		c.nonFinite = true
+	default:
+		// drop the sign of negative zero, including values that are
+		// rounded to zero by the format
+		s := format(v)
+		if strings.HasPrefix(s, "-") && strings.Trim(s[1:], "0.") == "" {
+			s = s[1:]
+		}
+		c.typ = CellTypeNumber
+		c.v = s
+	}
+}
+
+// SetValue stores a value of any of the types supported by the typed
+// setters: integers go through SetInt, floating point numbers through
+// SetFloat, strings through SetStr, time.Time through SetDateTime, and
+// bools through SetBool; nil (or a nil pointer) clears the cell. Pointers
+// are followed and named types are accepted by their underlying kind.
+func (c *Cell) SetValue(v any) error {
+	return setReflectValue(c, reflect.ValueOf(v))
 }
 
 func (c *Cell) SetStr(v string) {
@@ -69,15 +212,145 @@ func (c *Cell) SetStr(v string) {
 	c.v = v
 }
 
+// SetInlineStr stores a string in the cell itself rather than in the
+// shared string table. Inlining avoids the table overhead for strings that
+// are unlikely to repeat, such as identifiers or free-form notes, while
+// SetStr stores repeated strings only once.
+func (c *Cell) SetInlineStr(v string) {
+	c.typ = CellTypeInlineString
+	c.v = v
+}
+
+// SetTextNumber stores a numeric-looking string, such as a ZIP code or a
+// part number, as text with the "@" number format, so that Excel keeps it
+// verbatim ("007" stays "007" instead of becoming 7). Use SetInt or
+// SetFloat for values that are meant to be used as numbers.
+func (c *Cell) SetTextNumber(s string) {
+	c.SetStr(s)
+	c.NumFmt = "@"
+}
+
+// SetSharedIndex makes the cell refer to a string that was interned in
+// advance with Writer.InternStrings or Writer.SharedString. The index must
+// refer to a string interned before Writer.Write is called, which checks it
+// before anything is written.
+func (c *Cell) SetSharedIndex(i int) {
+	c.typ = cellTypeSharedIndex
+	c.v = strconv.Itoa(i)
+}
+
+// SetPicture places a picture in the cell, see PictureInfo for how it is
+// sized. The picture is checked when the workbook is written, use
+// SetPictureValidated to catch unsupported formats right away.
 func (c *Cell) SetPicture(p *PictureInfo) {
 	c.typ = cellTypePicture
 	c.picture = p
 }
 
+// SetPictureValidated is SetPicture that first checks that the picture has
+// data and a supported extension (".png", ".jpg" or ".jpeg"), the cell is
+// left unchanged on error.
+func (c *Cell) SetPictureValidated(p *PictureInfo) error {
+	if _, _, err := pictureType(p); err != nil {
+		return err
+	}
+	c.SetPicture(p)
+	return nil
+}
+
+// SetComment attaches a comment to the cell, replacing any existing one.
+func (c *Cell) SetComment(author, text string) {
+	c.comment = &Comment{Author: author, Text: text}
+}
+
+// SetCommentWithSize attaches a comment with a custom box size in pixels,
+// which keeps long or multi-line comments from being clipped.
+func (c *Cell) SetCommentWithSize(author, text string, widthPx, heightPx int) {
+	c.comment = &Comment{Author: author, Text: text, Width: widthPx, Height: heightPx}
+}
+
+// Comment returns the comment attached to the cell, or nil.
+func (c *Cell) Comment() *Comment {
+	return c.comment
+}
+
+// Clear resets the cell back to an unset value and removes its hyperlink,
+// the cell style and comment are kept.
+func (c *Cell) Clear() {
+	c.typ = CellTypeUnset
+	c.v = ""
+	c.hyperlink = nil
+	c.nonFinite = false
+	c.dateTime = false
+	c.picture = nil
+}
+
+// Reset clears both the value and the style of the cell.
+func (c *Cell) Reset() {
+	c.Clear()
+	c.XF = XF{}
+}
+
 func (a *Alignment) Empty() bool {
-	return a.Horizontal == "" && a.Vertical == ""
+	return *a == Alignment{}
+}
+
+// Fill is the background of a cell. Colors are RGB as "FFFF00" or
+// "#FFFF00", or ARGB.
+type Fill struct {
+	PatternType string // e.g. "solid", "gray125", "lightGrid"; solid when empty and FgColor is set
+	FgColor     string // pattern color, the background color for solid fills
+	BgColor     string // color behind the pattern, unused for solid fills
+}
+
+// patternTypes are the fill patterns defined by ECMA-376 (ST_PatternType).
+var patternTypes = []string{"none", "solid", "mediumGray", "darkGray", "lightGray",
+	"darkHorizontal", "darkVertical", "darkDown", "darkUp", "darkGrid", "darkTrellis",
+	"lightHorizontal", "lightVertical", "lightDown", "lightUp", "lightGrid", "lightTrellis",
+	"gray125", "gray0625"}
+
+// Border describes the edges drawn around a cell, edges without a style
+// are not drawn. The diagonal edge is drawn in the directions selected by
+// DiagonalUp and DiagonalDown.
+type Border struct {
+	Left     BorderEdge
+	Right    BorderEdge
+	Top      BorderEdge
+	Bottom   BorderEdge
+	Diagonal BorderEdge
+
+	DiagonalUp   bool
+	DiagonalDown bool
+}
+
+// BorderEdge is a single edge of a border. Style is one of "thin",
+// "medium", "thick", "double", "hair", "dotted", "dashed", "dashDot",
+// "dashDotDot", "mediumDashed", "mediumDashDot", "mediumDashDotDot" or
+// "slantDashDot". Color is RGB as "000000" or "#000000", or ARGB; empty
+// for automatic.
+type BorderEdge struct {
+	Style string
+	Color string
+}
+
+// borderStyles are the edge styles defined by ECMA-376 (ST_BorderStyle).
+var borderStyles = []string{"none", "thin", "medium", "dashed", "dotted", "thick",
+	"double", "hair", "mediumDashed", "dashDot", "mediumDashDot", "dashDotDot",
+	"mediumDashDotDot", "slantDashDot"}
+
+func (b *Border) Empty() bool {
+	return *b == Border{}
+}
+
+func (f *Fill) Empty() bool {
+	return *f == Fill{}
+}
+
+func (f *Font) Empty() bool {
+	return *f == Font{} || *f == Font{VertAlign: VertAlignBaseline}
 }
 
 func (xf *XF) Empty() bool {
-	return xf.Alignment.Empty()
+	return xf.NumFmt == "" && xf.Font.Empty() && xf.Fill.Empty() && xf.Border.Empty() &&
+		xf.Alignment.Empty() && xf.Protection.Empty() && xf.Style == ""
 }
@@ -0,0 +1,135 @@
+package xl
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+// tenth is a variable, so that 0.1+0.2 is computed in floating point
+// rather than as an exact constant
+var tenth = 0.1
+
+func TestSetFloat(t *testing.T) {
+	for _, tc := range []struct {
+		v    float64
+		want string
+	}{
+		{0.1, "0.1"},
+		{tenth + 0.2, "0.30000000000000004"},
+		{1e21, "1e+21"},
+		{-1.5e-300, "-1.5e-300"},
+		{math.MaxFloat64, "1.7976931348623157e+308"},
+		{math.Copysign(0, -1), "0"},
+	} {
+		c := &Cell{}
+		c.SetFloat(tc.v)
+		if c.Type() != CellTypeNumber || c.Value() != tc.want {
+			t.Errorf("SetFloat(%g) = %q, want %q", tc.v, c.Value(), tc.want)
+		}
+	}
+}
+
+func TestSetFloatPrec(t *testing.T) {
+	for _, tc := range []struct {
+		v    float64
+		prec int
+		want string
+	}{
+		{tenth + 0.2, 2, "0.30"},
+		{0.1, 0, "0"},
+		{2.5, -1, "2"}, // negative precision is 0, rounding half to even
+		{1e20, 2, "100000000000000000000.00"},
+		{-123456789.987654321, 3, "-123456789.988"},
+		{math.Copysign(0, -1), 2, "0.00"},
+		{-0.001, 2, "0.00"},
+		{-0.005001, 2, "-0.01"},
+	} {
+		c := &Cell{}
+		c.SetFloatPrec(tc.v, tc.prec)
+		if c.Type() != CellTypeNumber || c.Value() != tc.want {
+			t.Errorf("SetFloatPrec(%g, %d) = %q, want %q", tc.v, tc.prec, c.Value(), tc.want)
+		}
+	}
+
+	c := &Cell{}
+	c.SetFloatPrec(math.NaN(), 2)
+	if c.Type() != CellTypeError || c.Value() != "#NUM!" {
+		t.Errorf("SetFloatPrec(NaN) = %v %q", c.Type(), c.Value())
+	}
+}
+
+func TestNonFinite(t *testing.T) {
+	f32 := float32(math.Inf(-1))
+	setters := map[string]func(c *Cell, v float64){
+		"SetFloat":     func(c *Cell, v float64) { c.SetFloat(v) },
+		"SetFloatPrec": func(c *Cell, v float64) { c.SetFloatPrec(v, 2) },
+		"SetValue": func(c *Cell, v float64) {
+			if err := c.SetValue(v); err != nil {
+				t.Fatal(err)
+			}
+		},
+		"SetValue pointer": func(c *Cell, v float64) {
+			if err := c.SetValue(&v); err != nil {
+				t.Fatal(err)
+			}
+		},
+	}
+	for name, set := range setters {
+		for v, want := range map[float64]string{math.Inf(1): "#DIV/0!", math.Inf(-1): "#DIV/0!"} {
+			c := &Cell{}
+			set(c, v)
+			if c.Type() != CellTypeError || c.Value() != want || !c.nonFinite {
+				t.Errorf("%s(%g) = %v %q, want %q", name, v, c.Type(), c.Value(), want)
+			}
+		}
+		c := &Cell{}
+		set(c, math.NaN())
+		if c.Type() != CellTypeError || c.Value() != "#NUM!" || !c.nonFinite {
+			t.Errorf("%s(NaN) = %v %q, want #NUM!", name, c.Type(), c.Value())
+		}
+		// a finite value replaces the error
+		set(c, 1)
+		if c.Type() != CellTypeNumber || c.nonFinite {
+			t.Errorf("%s(1) after NaN = %v %q", name, c.Type(), c.Value())
+		}
+	}
+	c := &Cell{}
+	if err := c.SetValue(f32); err != nil || c.Value() != "#DIV/0!" {
+		t.Errorf("SetValue(float32 -Inf) = %q, %v", c.Value(), err)
+	}
+
+	wb, sh := newTestSheet(t, "Data")
+	mustCell(t, sh, "A1").SetFloat(math.NaN())
+	mustCell(t, sh, "A2").SetFloat(math.Inf(1))
+	wantContains(t, writeParts(t, wb).part(t, "/xl/worksheets/sheet1.xml"),
+		`<c r="A1" t="e"><v>#NUM!</v></c>`, `<c r="A2" t="e"><v>#DIV/0!</v></c>`)
+	w := NewWriter(memStorage{})
+	w.RejectNonFinite = true
+	if err := w.Write(wb); err == nil || err.Error() != "sheet 'Data', cell A1: non-finite number" {
+		t.Errorf("got %v, want a non-finite number error", err)
+	}
+}
+
+func TestClear(t *testing.T) {
+	wb, sh := newTestSheet(t, "Data")
+	c := mustCell(t, sh, "A1")
+	c.SetHyperlink("https://example.com/", "example")
+	c.SetComment("me", "note")
+	c.Clear()
+	if c.Type() != CellTypeUnset || c.Hyperlink() != nil || c.Comment() == nil {
+		t.Errorf("got %v, link %v, comment %v", c.Type(), c.Hyperlink(), c.Comment())
+	}
+	c = mustCell(t, sh, "A2")
+	c.SetLocationLink("Data!A1", "top")
+	c.Clear()
+
+	m := writeParts(t, wb)
+	s := m.part(t, "/xl/worksheets/sheet1.xml")
+	if strings.Contains(s, "<hyperlink") {
+		t.Error("cleared hyperlink written")
+	}
+	if strings.Contains(m.part(t, "/xl/worksheets/_rels/sheet1.xml.rels"), "example.com") {
+		t.Error("cleared hyperlink relationship written")
+	}
+}
@@ -0,0 +1,150 @@
+package xl
+
+import "slices"
+
+// Clone returns a deep copy of the workbook, which can be modified
+// independently of the original, e.g. to produce several reports from a
+// common template.
+//
+// Picture blobs are not copied, the cells of both workbooks refer to the
+// same PictureInfo values. They are never modified by the package, replace
+// the picture of a cell with SetPicture rather than altering the shared
+// PictureInfo.
+func (wb *Workbook) Clone() *Workbook {
+	c := &Workbook{
+		AppName:        wb.AppName,
+		Company:        wb.Company,
+		Manager:        wb.Manager,
+		Strict:         wb.Strict,
+		Settings:       wb.Settings,
+		CalcProperties: wb.CalcProperties,
+		CoreProperties: wb.CoreProperties,
+		Date1904:       wb.Date1904,
+		WindowWidth:    wb.WindowWidth,
+		WindowHeight:   wb.WindowHeight,
+
+		sheetMap:         make(map[string]*Sheet, len(wb.sheetMap)),
+		lastIdN:          wb.lastIdN,
+		themeColors:      wb.themeColors,
+		definedNameIndex: make(map[definedNameKey]*DefinedName, len(wb.definedNames)),
+		vbaProject:       wb.vbaProject,
+	}
+
+	sheets := make(map[*Sheet]*Sheet, len(wb.Sheets))
+	for _, sh := range wb.Sheets {
+		csh := sh.clone(c)
+		sheets[sh] = csh
+		c.Sheets = append(c.Sheets, csh)
+		c.sheetMap[csh.Name] = csh
+	}
+	if wb.ActiveSheet != nil {
+		c.ActiveSheet = sheets[wb.ActiveSheet]
+	}
+
+	for _, dn := range wb.definedNames {
+		cdn := *dn
+		if dn.Sheet != nil {
+			cdn.Sheet = sheets[dn.Sheet]
+		}
+		c.definedNames = append(c.definedNames, &cdn)
+		c.definedNameIndex[makeDefinedNameKey(cdn.Name, cdn.Sheet)] = &cdn
+	}
+
+	for _, ns := range wb.namedStyles {
+		cns := *ns
+		c.namedStyles = append(c.namedStyles, &cns)
+	}
+
+	for _, p := range wb.customProperties {
+		cp := *p
+		c.customProperties = append(c.customProperties, &cp)
+	}
+
+	return c
+}
+
+// clone returns a deep copy of the sheet, owned by wb.
+func (s *Sheet) clone(wb *Workbook) *Sheet {
+	// start from a shallow copy so that plain fields are carried over, then
+	// replace everything that is shared by reference
+	cs := *s
+	c := &cs
+	c.workbook = wb
+	c.Columns = make(map[int]*Column, len(s.Columns))
+	c.Rows = nil
+	c.merges = slices.Clone(s.merges)
+	c.mergeIndex = s.mergeIndex.clone()
+	c.condFormats = nil
+	c.dataValidations = nil
+
+	if s.EnableFormatConditionsCalculation != nil {
+		v := *s.EnableFormatConditionsCalculation
+		c.EnableFormatConditionsCalculation = &v
+	}
+	if s.ShowGridlines != nil {
+		v := *s.ShowGridlines
+		c.ShowGridlines = &v
+	}
+	if s.ShowRowColHeaders != nil {
+		v := *s.ShowRowColHeaders
+		c.ShowRowColHeaders = &v
+	}
+
+	for n, col := range s.Columns {
+		cc := *col
+		c.Columns[n] = &cc
+	}
+
+	for _, r := range s.Rows {
+		c.Rows = append(c.Rows, r.clone(c))
+	}
+
+	if s.pane != nil {
+		p := *s.pane
+		c.pane = &p
+	}
+	if s.protection != nil {
+		p := *s.protection
+		c.protection = &p
+	}
+	if s.printArea != nil {
+		pa := *s.printArea
+		c.printArea = &pa
+	}
+
+	for _, cf := range s.condFormats {
+		ccf := *cf
+		ccf.rule.Icons = slices.Clone(cf.rule.Icons)
+		c.condFormats = append(c.condFormats, &ccf)
+	}
+
+	for _, dv := range s.dataValidations {
+		cdv := *dv
+		c.dataValidations = append(c.dataValidations, &cdv)
+	}
+
+	return c
+}
+
+// clone returns a deep copy of the row, owned by sh.
+func (r *Row) clone(sh *Sheet) *Row {
+	// as with sheets, plain fields are carried over by the shallow copy
+	cr := *r
+	c := &cr
+	c.sheet = sh
+	c.Cells = make([]*Cell, 0, len(r.Cells))
+	for _, cell := range r.Cells {
+		cc := *cell
+		cc.row = c
+		if cell.comment != nil {
+			cm := *cell.comment
+			cc.comment = &cm
+		}
+		if cell.hyperlink != nil {
+			h := *cell.hyperlink
+			cc.hyperlink = &h
+		}
+		c.Cells = append(c.Cells, &cc)
+	}
+	return c
+}
@@ -0,0 +1,31 @@
+package xl
+
+import "testing"
+
+func TestCloneRows(t *testing.T) {
+	wb, sh := newTestSheet(t, "Sheet1")
+	mustCell(t, sh, "A1").SetStr("a")
+	mustCell(t, sh, "A2").SetStr("b")
+	if err := sh.GroupRows(1, 2, 1); err != nil {
+		t.Fatal(err)
+	}
+	r := sh.Rows[1]
+	r.Hidden, r.Collapsed, r.Height = true, true, 20
+
+	c := wb.Clone()
+	csh := c.Sheets[0]
+	for i, cr := range csh.Rows {
+		r := sh.Rows[i]
+		if cr.OutlineLevel != r.OutlineLevel || cr.Hidden != r.Hidden || cr.Collapsed != r.Collapsed ||
+			cr.Height != r.Height || cr.rowNumber != r.rowNumber {
+			t.Errorf("row %d: clone %+v differs from %+v", i+1, *cr, *r)
+		}
+		if cr.sheet != csh {
+			t.Errorf("row %d: clone belongs to the original sheet", i+1)
+		}
+	}
+	mustCell(t, csh, "A1").SetStr("changed")
+	if v := mustCell(t, sh, "A1").Value(); v != "a" {
+		t.Errorf("original cell changed to %q", v)
+	}
+}
@@ -0,0 +1,83 @@
+package xl
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestCommentShapeIDs(t *testing.T) {
+	wb := NewWorkbook()
+	for i, count := range []int{1100, 1} {
+		sh, err := wb.AddSheet(string(rune('A' + i)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for r := 1; r <= count; r++ {
+			mustCell(t, sh, CellCoordAsString(1, r)).SetComment("me", "note")
+		}
+	}
+	m := writeParts(t, wb)
+
+	vml1 := m.part(t, "/xl/drawings/vmlDrawing1.vml")
+	vml2 := m.part(t, "/xl/drawings/vmlDrawing2.vml")
+	wantContains(t, vml1, `<o:idmap v:ext="edit" data="1,2"/>`, `id="_x0000_s1025"`, `id="_x0000_s2125"`)
+	wantContains(t, vml2, `<o:idmap v:ext="edit" data="3"/>`, `id="_x0000_s3073"`)
+
+	ids := map[string]bool{}
+	re := regexp.MustCompile(`id="(_x0000_s\d+)"`)
+	for _, vml := range []string{vml1, vml2} {
+		for _, m := range re.FindAllStringSubmatch(vml, -1) {
+			if ids[m[1]] {
+				t.Errorf("duplicate shape id %s", m[1])
+			}
+			ids[m[1]] = true
+		}
+	}
+	if len(ids) != 1101 {
+		t.Errorf("got %d shape ids, want 1101", len(ids))
+	}
+}
+
+// TestCommentShapeBlocks checks that a block of shape ids holds 1023
+// shapes, the 1024th starts a new block.
+func TestCommentShapeBlocks(t *testing.T) {
+	for _, tc := range []struct {
+		count  int
+		idmap  string
+		lastID string
+	}{
+		{1023, `data="1"`, `id="_x0000_s2047"`},
+		{1024, `data="1,2"`, `id="_x0000_s2049"`},
+	} {
+		wb, sh := newTestSheet(t, "Data")
+		for r := 1; r <= tc.count; r++ {
+			mustCell(t, sh, CellCoordAsString(1, r)).SetComment("me", "note")
+		}
+		vml := writeParts(t, wb).part(t, "/xl/drawings/vmlDrawing1.vml")
+		wantContains(t, vml, `<o:idmap v:ext="edit" `+tc.idmap+`/>`, tc.lastID)
+		if strings.Contains(vml, `id="_x0000_s2048"`) {
+			t.Errorf("%d shapes: the reserved id 2048 is used", tc.count)
+		}
+	}
+}
+
+func TestComments(t *testing.T) {
+	wb, sh := newTestSheet(t, "Sheet1")
+	mustCell(t, sh, "B2").SetComment("Ann", "first")
+	mustCell(t, sh, "C3").SetCommentWithSize("Bob", "second", 200, 100)
+	mustCell(t, sh, "D4").SetComment("Ann", "third")
+	m := writeParts(t, wb)
+
+	wantContains(t, m.part(t, "/xl/comments1.xml"),
+		"<author>Ann</author>", "<author>Bob</author>",
+		`<comment ref="B2" authorId="0">`, `<comment ref="C3" authorId="1">`, `<comment ref="D4" authorId="0">`,
+		`<t xml:space="preserve">second</t>`)
+	wantContains(t, m.part(t, "/xl/worksheets/_rels/sheet1.xml.rels"),
+		`Target="../comments1.xml"`, `Target="../drawings/vmlDrawing1.vml"`)
+	wantContains(t, m.part(t, "/xl/worksheets/sheet1.xml"), `<legacyDrawing r:id="rId2"/>`)
+	// the box of C3 is 200x100 px, written in points
+	wantContains(t, m.part(t, "/xl/drawings/vmlDrawing1.vml"),
+		"width:150pt;height:75pt", "<x:Row>2</x:Row>", "<x:Column>2</x:Column>")
+	wantContains(t, m.part(t, "[Content_Types].xml"), `Extension="vml"`, `PartName="/xl/comments1.xml"`)
+}
@@ -0,0 +1,271 @@
+package xl
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// CondFormatType selects the kind of a conditional formatting rule.
+type CondFormatType int
+
+// Conditional formatting rule types.
+const (
+	CondFormatDataBar CondFormatType = iota + 1
+	CondFormatIconSet
+	CondFormatCellIs
+	CondFormatColorScale
+)
+
+// CondFormatRule is a conditional formatting rule applied to a range of
+// cells with Sheet.AddConditionalFormat.
+//
+// Rules are written in the classic SpreadsheetML form unless Extended is
+// set, which selects the Excel 2010 (x14) form stored in the extension list
+// of the worksheet. The options marked as extended below can only be
+// expressed in that form and are rejected otherwise. Excel 2007 ignores
+// extended rules. Cell value rules and color scales are classic only.
+type CondFormatRule struct {
+	Type       CondFormatType
+	StopIfTrue bool
+	Extended   bool
+
+	// data bar options
+	BarColor      string // RRGGBB, defaults to 638EC6
+	SolidFill     bool   // extended: plain fill instead of a gradient
+	NegativeColor string // extended: RRGGBB fill of negative bars, defaults to FF0000
+
+	// icon set options
+	IconSet      string // e.g. "3Arrows", defaults to "3TrafficLights1"
+	ReverseIcons bool
+	Icons        []CondFormatIcon // extended: custom icons, one per threshold
+
+	// cell value options, formulas are written without the leading '='
+	Operator string     // cellIs operator, e.g. "greaterThan" or "between", required
+	Formula  string     // compared value, e.g. "100" or "$B$1"
+	Formula2 string     // upper bound of the "between" and "notBetween" operators
+	Format   DiffFormat // applied to the matching cells
+
+	// color scale options, a middle color makes a 3-color scale
+	MinColor string // RRGGBB, defaults to F8696B
+	MidColor string // RRGGBB, optional
+	MaxColor string // RRGGBB, defaults to 63BE7B
+}
+
+// DiffFormat is a differential format, which changes some aspects of the
+// format of a cell and leaves the others as they are. Colors are RRGGBB.
+type DiffFormat struct {
+	NumFmt    string
+	Bold      bool
+	Italic    bool
+	FontColor string
+	FillColor string
+}
+
+// Empty reports whether the format changes nothing.
+func (f *DiffFormat) Empty() bool {
+	return *f == DiffFormat{}
+}
+
+func (f *DiffFormat) normalize() error {
+	var err error
+	for _, c := range []*string{&f.FontColor, &f.FillColor} {
+		if *c == "" {
+			continue
+		}
+		if *c, err = normalizeRGB(*c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CondFormatIcon picks an individual icon of an icon set, Index is 0-based.
+type CondFormatIcon struct {
+	IconSet string
+	Index   int
+}
+
+// classicIconSets lists the icon sets of the classic form, the x14 form adds
+// x14IconSets.
+var classicIconSets = []string{
+	"3Arrows", "3ArrowsGray", "3Flags", "3TrafficLights1", "3TrafficLights2",
+	"3Signs", "3Symbols", "3Symbols2", "4Arrows", "4ArrowsGray", "4RedToBlack",
+	"4Rating", "4TrafficLights", "5Arrows", "5ArrowsGray", "5Rating", "5Quarters",
+}
+
+var x14IconSets = []string{"3Stars", "3Triangles", "5Boxes"}
+
+// conditionalFormat is a rule bound to a range, priority is sheet-wide and
+// 1-based.
+type conditionalFormat struct {
+	sqref    cellRange
+	rule     CondFormatRule
+	priority int
+}
+
+// AddConditionalFormat applies a conditional formatting rule to a range of
+// cells specified as "A1:A10" or a single cell reference. Rules are
+// evaluated in the order they are added.
+func (s *Sheet) AddConditionalFormat(ref string, rule CondFormatRule) error {
+	if !strings.Contains(ref, ":") {
+		ref += ":" + ref
+	}
+	r, err := parseMergeCellRef(ref)
+	if err != nil {
+		return err
+	}
+	if err = rule.normalize(); err != nil {
+		return err
+	}
+	s.condFormats = append(s.condFormats, &conditionalFormat{
+		sqref:    r,
+		rule:     rule,
+		priority: len(s.condFormats) + 1,
+	})
+	return nil
+}
+
+// normalize validates the rule and fills in the defaults.
+func (r *CondFormatRule) normalize() error {
+	var err error
+	switch r.Type {
+	case CondFormatDataBar:
+		if r.BarColor == "" {
+			r.BarColor = "638EC6"
+		}
+		if r.BarColor, err = normalizeRGB(r.BarColor); err != nil {
+			return err
+		}
+		if !r.Extended && (r.SolidFill || r.NegativeColor != "") {
+			return fmt.Errorf("data bar fill options require an extended rule")
+		}
+		if r.NegativeColor == "" {
+			r.NegativeColor = "FF0000"
+		}
+		if r.NegativeColor, err = normalizeRGB(r.NegativeColor); err != nil {
+			return err
+		}
+
+	case CondFormatIconSet:
+		if r.IconSet == "" {
+			r.IconSet = "3TrafficLights1"
+		}
+		if err = checkIconSet(r.IconSet, r.Extended); err != nil {
+			return err
+		}
+		if len(r.Icons) > 0 {
+			if !r.Extended {
+				return fmt.Errorf("custom icons require an extended rule")
+			}
+			if len(r.Icons) != r.iconCount() {
+				return fmt.Errorf("icon set '%s' needs %d icons, got %d", r.IconSet, r.iconCount(), len(r.Icons))
+			}
+			for _, icon := range r.Icons {
+				if err = checkIconSet(icon.IconSet, true); err != nil {
+					return err
+				}
+				if icon.Index < 0 || icon.Index >= iconSetSize(icon.IconSet) {
+					return fmt.Errorf("invalid icon index %d in set '%s'", icon.Index, icon.IconSet)
+				}
+			}
+		}
+
+	case CondFormatCellIs:
+		if r.Extended {
+			return fmt.Errorf("cell value rules can not be extended")
+		}
+		r.Formula = strings.TrimPrefix(r.Formula, "=")
+		r.Formula2 = strings.TrimPrefix(r.Formula2, "=")
+		// the cellIs operators are the same as those of data validations
+		if !slices.Contains(dataValidationOperators, r.Operator) {
+			return fmt.Errorf("invalid conditional format operator '%s'", r.Operator)
+		}
+		if r.Formula == "" {
+			return fmt.Errorf("cell value rule requires a formula")
+		}
+		between := r.Operator == "between" || r.Operator == "notBetween"
+		if between && r.Formula2 == "" {
+			return fmt.Errorf("operator '%s' requires two formulas", r.Operator)
+		}
+		if !between && r.Formula2 != "" {
+			return fmt.Errorf("operator '%s' takes a single formula", r.Operator)
+		}
+		if r.Format.Empty() {
+			return fmt.Errorf("cell value rule requires a format")
+		}
+		if err = r.Format.normalize(); err != nil {
+			return err
+		}
+
+	case CondFormatColorScale:
+		if r.Extended {
+			return fmt.Errorf("color scales can not be extended")
+		}
+		if r.MinColor == "" {
+			r.MinColor = "F8696B"
+		}
+		if r.MaxColor == "" {
+			r.MaxColor = "63BE7B"
+		}
+		for _, c := range []*string{&r.MinColor, &r.MidColor, &r.MaxColor} {
+			if *c == "" {
+				continue
+			}
+			if *c, err = normalizeRGB(*c); err != nil {
+				return err
+			}
+		}
+
+	default:
+		return fmt.Errorf("invalid conditional format type: %d", r.Type)
+	}
+	return nil
+}
+
+func checkIconSet(name string, extended bool) error {
+	if slices.Contains(classicIconSets, name) {
+		return nil
+	}
+	if slices.Contains(x14IconSets, name) {
+		if !extended {
+			return fmt.Errorf("icon set '%s' requires an extended rule", name)
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown icon set '%s'", name)
+}
+
+// iconSetSize returns the number of icons in a set, which is encoded in the
+// first character of its name.
+func iconSetSize(name string) int {
+	return int(name[0] - '0')
+}
+
+func (r *CondFormatRule) iconCount() int {
+	return iconSetSize(r.IconSet)
+}
+
+// iconThresholds returns the percent thresholds that split the range evenly
+// between the icons, as Excel does by default.
+func (r *CondFormatRule) iconThresholds() []int {
+	n := r.iconCount()
+	t := make([]int, n)
+	for i := range t {
+		t[i] = (i*100 + n/2) / n
+	}
+	return t
+}
+
+// classic reports whether the rule has a classic cfRule element. Extended
+// data bars have both forms linked by id, so that Excel 2007 still shows
+// the bars; icon sets are written in one form only.
+func (cf *conditionalFormat) classic() bool {
+	return !cf.rule.Extended || cf.rule.Type == CondFormatDataBar
+}
+
+// extID returns the id that links the classic and the extended form of a
+// rule.
+func (cf *conditionalFormat) extID() string {
+	return fmt.Sprintf("{00000000-0000-0000-0000-%012X}", cf.priority)
+}
@@ -0,0 +1,99 @@
+package xl
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestConditionalFormats(t *testing.T) {
+	wb, sh := newTestSheet(t, "Sheet1")
+	red := DiffFormat{Bold: true, FontColor: "9c0006", FillColor: "#FFC7CE"}
+	for _, tc := range []struct {
+		ref  string
+		rule CondFormatRule
+	}{
+		{"A1:A10", CondFormatRule{Type: CondFormatCellIs, Operator: "greaterThan", Formula: "=100", Format: red}},
+		{"A1:A10", CondFormatRule{Type: CondFormatCellIs, Operator: "between", Formula: "10", Formula2: "$B$1",
+			Format: DiffFormat{NumFmt: "0.000"}}},
+		{"B1:B10", CondFormatRule{Type: CondFormatColorScale}},
+		{"C1:C10", CondFormatRule{Type: CondFormatColorScale, MidColor: "FFEB84"}},
+		// the same format as the first rule shares its dxf
+		{"D1", CondFormatRule{Type: CondFormatCellIs, Operator: "lessThan", Formula: "0", Format: red}},
+	} {
+		if err := sh.AddConditionalFormat(tc.ref, tc.rule); err != nil {
+			t.Fatal(err)
+		}
+	}
+	m := writeParts(t, wb)
+
+	s := m.part(t, "/xl/worksheets/sheet1.xml")
+	wantContains(t, s,
+		`<cfRule type="cellIs" dxfId="0" priority="1" operator="greaterThan">
+      <formula>100</formula>
+    </cfRule>`,
+		`<cfRule type="cellIs" dxfId="1" priority="2" operator="between">
+      <formula>10</formula>
+      <formula>$B$1</formula>
+    </cfRule>`,
+		`<conditionalFormatting sqref="B1:B10">
+    <cfRule type="colorScale" priority="3">
+      <colorScale>
+        <cfvo type="min"/>
+        <cfvo type="max"/>
+        <color rgb="FFF8696B"/>
+        <color rgb="FF63BE7B"/>
+      </colorScale>`,
+		`<conditionalFormatting sqref="C1:C10">
+    <cfRule type="colorScale" priority="4">
+      <colorScale>
+        <cfvo type="min"/>
+        <cfvo type="percentile" val="50"/>
+        <cfvo type="max"/>
+        <color rgb="FFF8696B"/>
+        <color rgb="FFFFEB84"/>
+        <color rgb="FF63BE7B"/>
+      </colorScale>`,
+		`<conditionalFormatting sqref="D1">
+    <cfRule type="cellIs" dxfId="0" priority="5" operator="lessThan">`)
+	// rules are written in the order of their priority
+	if i, j := strings.Index(s, `priority="1"`), strings.Index(s, `priority="5"`); i < 0 || j < i {
+		t.Error("rules are not ordered by priority")
+	}
+
+	styles := m.part(t, "/xl/styles.xml")
+	wantContains(t, styles,
+		`<numFmt numFmtId="164" formatCode="0.000"/>`,
+		`<dxfs count="2">
+    <dxf>
+      <font><b/><color rgb="FF9C0006"/></font>
+      <fill><patternFill><bgColor rgb="FFFFC7CE"/></patternFill></fill>
+    </dxf>
+    <dxf>
+      <numFmt numFmtId="164" formatCode="0.000"/>
+    </dxf>
+  </dxfs>`)
+	// dxfs follow cellStyles
+	if strings.Index(styles, "<dxfs") < strings.Index(styles, "</cellStyles>") {
+		t.Error("dxfs written before cellStyles")
+	}
+}
+
+func TestConditionalFormatErrors(t *testing.T) {
+	_, sh := newTestSheet(t, "Sheet1")
+	format := DiffFormat{Bold: true}
+	for _, rule := range []CondFormatRule{
+		{Type: CondFormatCellIs, Operator: "bogus", Formula: "1", Format: format},
+		{Type: CondFormatCellIs, Operator: "equal", Format: format},
+		{Type: CondFormatCellIs, Operator: "between", Formula: "1", Format: format},
+		{Type: CondFormatCellIs, Operator: "equal", Formula: "1", Formula2: "2", Format: format},
+		{Type: CondFormatCellIs, Operator: "equal", Formula: "1"},
+		{Type: CondFormatCellIs, Operator: "equal", Formula: "1", Format: DiffFormat{FillColor: "red"}},
+		{Type: CondFormatCellIs, Operator: "equal", Formula: "1", Format: format, Extended: true},
+		{Type: CondFormatColorScale, MinColor: "nope"},
+		{Type: CondFormatColorScale, Extended: true},
+	} {
+		if err := sh.AddConditionalFormat("A1", rule); err == nil {
+			t.Errorf("accepted %+v", rule)
+		}
+	}
+}
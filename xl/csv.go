@@ -0,0 +1,75 @@
+package xl
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// CSVOptions controls the output of Sheet.ExportCSV.
+type CSVOptions struct {
+	Comma   rune // field delimiter, ',' when zero; use '\t' for TSV
+	UseCRLF bool // terminate records with \r\n as RFC 4180 specifies
+}
+
+// ExportCSV writes the cell values of the sheet as CSV, quoting fields as
+// described in RFC 4180. Values are rendered as stored: numbers in their
+// shortest form, booleans as TRUE/FALSE, errors as their code (#N/A).
+// Pictures produce empty fields. Cells hold no formulas, the reader keeps
+// only their cached results, which are written as any other value. Every
+// record has the same number of fields, blank cells and rows in between
+// are written as empty fields and records.
+//
+// Cells set with SetSharedIndex can not be exported, their strings belong
+// to a Writer, and return an error.
+func (s *Sheet) ExportCSV(w io.Writer, opts CSVOptions) error {
+	cw := csv.NewWriter(w)
+	if opts.Comma != 0 {
+		cw.Comma = opts.Comma
+	}
+	cw.UseCRLF = opts.UseCRLF
+
+	maxCol, _ := s.dataExtent()
+	record := make([]string, maxCol)
+	nextRow := 1
+	for _, r := range s.Rows {
+		clear(record)
+		for ; nextRow < r.rowNumber; nextRow++ {
+			if err := cw.Write(record); err != nil {
+				return err
+			}
+		}
+		for _, c := range r.Cells {
+			if c.typ == cellTypeSharedIndex {
+				return fmt.Errorf("sheet '%s', cell %s: shared string index can not be exported", s.Name, c.coord)
+			}
+			record[c.columnNumber-1] = c.csvValue()
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+		nextRow = r.rowNumber + 1
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func (c *Cell) csvValue() string {
+	switch c.typ {
+	case CellTypeBool:
+		if c.v == "1" {
+			return "TRUE"
+		}
+		return "FALSE"
+	case CellTypeNumber, CellTypeError, CellTypeSharedString, CellTypeInlineString:
+		return c.v
+	case CellTypeDate:
+		serial, _ := strconv.ParseFloat(c.v, 64)
+		if c.dateTime {
+			return serialDate(serial).Format("2006-01-02 15:04:05")
+		}
+		return serialDate(serial).Format("2006-01-02")
+	}
+	return ""
+}
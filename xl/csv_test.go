@@ -0,0 +1,39 @@
+package xl
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExportCSV(t *testing.T) {
+	_, sh := newTestSheet(t, "Data")
+	mustCell(t, sh, "A1").SetInt(1)
+	mustCell(t, sh, "B1").SetFloat(2.5)
+	mustCell(t, sh, "C1").SetBool(true)
+	mustCell(t, sh, "A3").SetStr(`say "hi", then go`)
+	mustCell(t, sh, "B3").SetDate(time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC))
+	mustCell(t, sh, "C3").SetDateTime(time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC))
+
+	for _, tc := range []struct {
+		opts CSVOptions
+		want string
+	}{
+		{CSVOptions{}, "1,2.5,TRUE\n,,\n\"say \"\"hi\"\", then go\",2024-03-01,2024-03-01 12:30:00\n"},
+		{CSVOptions{Comma: '\t', UseCRLF: true}, "1\t2.5\tTRUE\r\n\t\t\r\n\"say \"\"hi\"\", then go\"\t2024-03-01\t2024-03-01 12:30:00\r\n"},
+	} {
+		var sb strings.Builder
+		if err := sh.ExportCSV(&sb, tc.opts); err != nil {
+			t.Fatal(err)
+		}
+		if sb.String() != tc.want {
+			t.Errorf("%+v: got\n%q\nwant\n%q", tc.opts, sb.String(), tc.want)
+		}
+	}
+
+	mustCell(t, sh, "D4").SetSharedIndex(0)
+	var sb strings.Builder
+	if err := sh.ExportCSV(&sb, CSVOptions{}); err == nil || !strings.Contains(err.Error(), "cell D4") {
+		t.Errorf("got %v, want a shared string index error", err)
+	}
+}
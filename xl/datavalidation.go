@@ -0,0 +1,108 @@
+package xl
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// DataValidation restricts the values that can be entered in a range of
+// cells, see Sheet.AddDataValidation. Formulas are written without the
+// leading '='; a list of literal values is a quoted, comma separated
+// string such as `"Yes,No,Maybe"`, a list can also refer to a range, e.g.
+// "Lists!$A$1:$A$10".
+type DataValidation struct {
+	Type     string // "list", "whole", "decimal", "date", "time", "textLength" or "custom"
+	Operator string // comparison of numeric types, "between" when empty, see below
+	Formula1 string
+	Formula2 string // upper bound of the "between" and "notBetween" operators
+
+	AllowBlank bool
+
+	// HideDropDown hides the in-cell dropdown of list validations. It is
+	// stored as the showDropDown attribute, which, despite its name, hides
+	// the dropdown when set.
+	HideDropDown bool
+
+	// messages shown when a cell is selected (prompt) and when an invalid
+	// value is entered (error); empty strings select the Excel defaults
+	PromptTitle string
+	Prompt      string
+	ErrorTitle  string
+	Error       string
+}
+
+var dataValidationTypes = []string{"list", "whole", "decimal", "date", "time", "textLength", "custom"}
+
+var dataValidationOperators = []string{"between", "notBetween", "equal", "notEqual",
+	"lessThan", "lessThanOrEqual", "greaterThan", "greaterThanOrEqual"}
+
+// Excel limits the literal lists of list validations to 255 characters
+const maxDataValidationList = 255
+
+type dataValidation struct {
+	sqref cellRange
+	DataValidation
+}
+
+// AddDataValidation applies a data validation rule to a range of cells
+// specified as "A2:A100" or a single cell reference. The ranges of the
+// validations of a sheet must not overlap.
+func (s *Sheet) AddDataValidation(ref string, dv DataValidation) error {
+	if !strings.Contains(ref, ":") {
+		ref += ":" + ref
+	}
+	r, err := parseMergeCellRef(ref)
+	if err != nil {
+		return err
+	}
+	if err = dv.normalize(); err != nil {
+		return err
+	}
+	for _, v := range s.dataValidations {
+		if v.sqref.overlaps(r) {
+			return fmt.Errorf("data validation range %s overlaps %s", r, v.sqref)
+		}
+	}
+	s.dataValidations = append(s.dataValidations, &dataValidation{sqref: r, DataValidation: dv})
+	return nil
+}
+
+func (dv *DataValidation) normalize() error {
+	if !slices.Contains(dataValidationTypes, dv.Type) {
+		return fmt.Errorf("invalid data validation type '%s'", dv.Type)
+	}
+	dv.Formula1 = strings.TrimPrefix(dv.Formula1, "=")
+	dv.Formula2 = strings.TrimPrefix(dv.Formula2, "=")
+	if dv.Formula1 == "" {
+		return fmt.Errorf("%s data validation requires a formula", dv.Type)
+	}
+
+	switch dv.Type {
+	case "list":
+		if strings.HasPrefix(dv.Formula1, `"`) && len(dv.Formula1) > maxDataValidationList+2 {
+			return fmt.Errorf("data validation list exceeds %d characters", maxDataValidationList)
+		}
+		fallthrough
+	case "custom":
+		if dv.Operator != "" || dv.Formula2 != "" {
+			return fmt.Errorf("%s data validation does not take an operator", dv.Type)
+		}
+		return nil
+	}
+
+	if dv.Operator == "" {
+		dv.Operator = "between"
+	}
+	if !slices.Contains(dataValidationOperators, dv.Operator) {
+		return fmt.Errorf("invalid data validation operator '%s'", dv.Operator)
+	}
+	between := dv.Operator == "between" || dv.Operator == "notBetween"
+	if between && dv.Formula2 == "" {
+		return fmt.Errorf("operator '%s' requires two formulas", dv.Operator)
+	}
+	if !between && dv.Formula2 != "" {
+		return fmt.Errorf("operator '%s' takes a single formula", dv.Operator)
+	}
+	return nil
+}
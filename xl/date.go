@@ -0,0 +1,90 @@
+package xl
+
+import (
+	"errors"
+	"strconv"
+	"time"
+)
+
+// Default number formats of date cells, both are built into Excel and
+// displayed according to the regional settings.
+const (
+	DateFormat     = "mm-dd-yy"    // numFmtId 14
+	DateTimeFormat = "m/d/yy h:mm" // numFmtId 22
+)
+
+var (
+	minDate = time.Date(1900, 1, 1, 0, 0, 0, 0, time.UTC)
+	maxDate = time.Date(10000, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// serial dates count days from this epoch, see dateSerial
+	dateEpoch = time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+)
+
+// SetDate stores the date part of t, displayed with DateFormat unless the
+// cell has a number format of its own. Dates are serial day numbers in the
+// file, only years 1900 to 9999 can be represented. The wall clock of t is
+// used as is, the time zone is ignored.
+func (c *Cell) SetDate(t time.Time) error {
+	return c.setDate(time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC), false)
+}
+
+// SetDateTime is like SetDate, but it keeps the time of day, which is
+// displayed with DateTimeFormat by default.
+func (c *Cell) SetDateTime(t time.Time) error {
+	return c.setDate(time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC), true)
+}
+
+func (c *Cell) setDate(t time.Time, withTime bool) error {
+	serial, err := dateSerial(t)
+	if err != nil {
+		return err
+	}
+	c.typ = CellTypeDate
+	c.v = strconv.FormatFloat(serial, 'g', -1, 64)
+	c.nonFinite = false
+	c.dateTime = withTime
+	return nil
+}
+
+// dateSerial converts a UTC time to the serial number of the 1900 date
+// system: the number of days since 1899-12-30, with the time of day as the
+// fraction. Excel treats 1900 as a leap year, so the serials of January and
+// February 1900 are one less.
+func dateSerial(t time.Time) (float64, error) {
+	if t.Before(minDate) {
+		return 0, errors.New("dates before 1900 can not be represented")
+	}
+	if !t.Before(maxDate) {
+		return 0, errors.New("dates after 9999 can not be represented")
+	}
+	secs := t.Unix() - dateEpoch.Unix()
+	serial := (float64(secs) + float64(t.Nanosecond())/1e9) / 86400
+	if serial < 61 {
+		serial--
+	}
+	return serial, nil
+}
+
+// date1904Serial converts a serial of the 1900 date system, as stored in
+// date cells, to the 1904 date system, whose epoch is day 1462 of the 1900
+// system.
+func date1904Serial(v string) (string, error) {
+	serial, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return "", err
+	}
+	if serial < 1462 {
+		return "", errors.New("dates before 1904 can not be represented in the 1904 date system")
+	}
+	return strconv.FormatFloat(serial-1462, 'g', -1, 64), nil
+}
+
+// serialDate is the inverse of dateSerial.
+func serialDate(serial float64) time.Time {
+	if serial < 61 {
+		serial++
+	}
+	ms := int64(serial*86400e3 + 0.5)
+	return dateEpoch.Add(time.Duration(ms%86400e3)*time.Millisecond).AddDate(0, 0, int(ms/86400e3))
+}
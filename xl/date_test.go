@@ -0,0 +1,50 @@
+package xl
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestDate1904 writes the same dates in both date systems, the serials of
+// the 1904 system are 1462 days less.
+func TestDate1904(t *testing.T) {
+	for _, tc := range []struct {
+		date1904 bool
+		pr       string
+		a1, a2   string
+	}{
+		{false, "", "45356", "45356.5"},
+		{true, `<workbookPr date1904="1"/>`, "43894", "43894.5"},
+	} {
+		wb, sh := newTestSheet(t, "Data")
+		wb.Date1904 = tc.date1904
+		if err := mustCell(t, sh, "A1").SetDate(time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC)); err != nil {
+			t.Fatal(err)
+		}
+		if err := mustCell(t, sh, "A2").SetDateTime(time.Date(2024, 3, 5, 12, 0, 0, 0, time.UTC)); err != nil {
+			t.Fatal(err)
+		}
+		m := writeParts(t, wb)
+		book := m.part(t, "/xl/workbook.xml")
+		if tc.pr != "" {
+			wantContains(t, book, tc.pr)
+		} else if strings.Contains(book, "date1904") {
+			t.Error("date1904 written for the 1900 date system")
+		}
+		wantContains(t, m.part(t, "/xl/worksheets/sheet1.xml"),
+			`<c r="A1" s="1" t="n"><v>`+tc.a1+`</v></c>`,
+			`<c r="A2" s="2" t="n"><v>`+tc.a2+`</v></c>`)
+	}
+
+	// dates before 1904 can not be written in the 1904 system
+	wb, sh := newTestSheet(t, "Data")
+	wb.Date1904 = true
+	if err := mustCell(t, sh, "A1").SetDate(time.Date(1903, 12, 31, 0, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatal(err)
+	}
+	err := NewWriter(memStorage{}).Write(wb)
+	if err == nil || !strings.Contains(err.Error(), "cell A1: dates before 1904") {
+		t.Errorf("got %v, want a pre-1904 date error", err)
+	}
+}
@@ -0,0 +1,96 @@
+package xl
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// CoreProperties are the document metadata written to docProps/core.xml,
+// which Excel shows on the Info page and search tools index. Empty fields
+// are omitted; zero timestamps are set to the time of writing.
+type CoreProperties struct {
+	Title          string
+	Subject        string
+	Creator        string // author
+	Keywords       string
+	Description    string
+	LastModifiedBy string
+	Created        time.Time
+	Modified       time.Time
+}
+
+// customProperty is a named value stored in docProps/custom.xml
+type customProperty struct {
+	name  string
+	value any
+}
+
+// SetCustomProperty sets a custom document property. Supported values are
+// strings, integers, floats, booleans and time.Time; setting an existing
+// name replaces its value. Custom properties are written to the
+// docProps/custom.xml part, which is omitted when there are none.
+func (wb *Workbook) SetCustomProperty(name string, value any) error {
+	if name == "" {
+		return errors.New("empty custom property name is not allowed")
+	}
+	switch v := value.(type) {
+	case string, bool, time.Time:
+	case int:
+		value = int64(v)
+	case int8:
+		value = int64(v)
+	case int16:
+		value = int64(v)
+	case int32:
+		value = int64(v)
+	case int64:
+	case uint8:
+		value = int64(v)
+	case uint16:
+		value = int64(v)
+	case uint32:
+		value = int64(v)
+	case float32:
+		value = float64(v)
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return fmt.Errorf("custom property '%s': non-finite value", name)
+		}
+	default:
+		return fmt.Errorf("custom property '%s': unsupported value type %T", name, value)
+	}
+	for _, p := range wb.customProperties {
+		if p.name == name {
+			p.value = value
+			return nil
+		}
+	}
+	wb.customProperties = append(wb.customProperties, &customProperty{name: name, value: value})
+	return nil
+}
+
+// vtValue returns the vt: element name and the text content that encode a
+// custom property value
+func (p *customProperty) vtValue() (string, string) {
+	switch v := p.value.(type) {
+	case string:
+		return "vt:lpwstr", v
+	case bool:
+		if v {
+			return "vt:bool", "true"
+		}
+		return "vt:bool", "false"
+	case int64:
+		if v >= math.MinInt32 && v <= math.MaxInt32 {
+			return "vt:i4", fmt.Sprint(v)
+		}
+		return "vt:i8", fmt.Sprint(v)
+	case float64:
+		return "vt:r8", fmt.Sprint(v)
+	case time.Time:
+		return "vt:filetime", v.UTC().Format("2006-01-02T15:04:05Z")
+	}
+	panic("unexpected custom property type")
+}
@@ -0,0 +1,102 @@
+package xl
+
+import (
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCustomPropertiesPart(t *testing.T) {
+	wb, _ := newTestSheet(t, "Sheet1")
+	if _, ok := writeParts(t, wb)["/docProps/custom.xml"]; ok {
+		t.Error("custom.xml written without custom properties")
+	}
+
+	for _, p := range []struct {
+		name  string
+		value any
+	}{
+		{"Project", "Apollo"},
+		{"Count", 42},
+		{"Big", int64(1) << 40},
+		{"Ratio", 0.5},
+		{"Final", true},
+		{"Due", time.Date(2024, 3, 5, 14, 30, 0, 0, time.UTC)},
+		{"Project", "Gemini"}, // replaces the value
+	} {
+		if err := wb.SetCustomProperty(p.name, p.value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := wb.SetCustomProperty("Bad", struct{}{}); err == nil {
+		t.Error("accepted an unsupported value type")
+	}
+
+	m := writeParts(t, wb)
+	wantContains(t, m.part(t, "/docProps/custom.xml"),
+		`pid="2" name="Project"><vt:lpwstr>Gemini</vt:lpwstr>`,
+		`pid="3" name="Count"><vt:i4>42</vt:i4>`,
+		`pid="4" name="Big"><vt:i8>1099511627776</vt:i8>`,
+		`pid="5" name="Ratio"><vt:r8>0.5</vt:r8>`,
+		`pid="6" name="Final"><vt:bool>true</vt:bool>`,
+		`pid="7" name="Due"><vt:filetime>2024-03-05T14:30:00Z</vt:filetime>`)
+	wantContains(t, m.part(t, "/_rels/.rels"), `Target="docProps/custom.xml"`)
+	wantContains(t, m.part(t, "[Content_Types].xml"),
+		`PartName="/docProps/custom.xml" ContentType="application/vnd.openxmlformats-officedocument.custom-properties+xml"`)
+}
+
+// TestCustomPropertyTypes checks the vt: element of each Go type and that
+// every property carries the fmtid and a unique pid counting from 2.
+func TestCustomPropertyTypes(t *testing.T) {
+	wb, _ := newTestSheet(t, "Sheet1")
+	cet := time.FixedZone("CET", 3600)
+	for _, p := range []struct {
+		name  string
+		value any
+	}{
+		{"Small", int8(-5)},
+		{"MaxI4", int64(math.MaxInt32)},
+		{"MinI4", int32(math.MinInt32)},
+		{"Wide", uint32(math.MaxUint32)},
+		{"Half", float32(0.5)},
+		{"Draft", false},
+		{"Local", time.Date(2024, 1, 2, 0, 30, 0, 0, cet)},
+		{"Empty", ""},
+	} {
+		if err := wb.SetCustomProperty(p.name, p.value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, v := range []any{math.NaN(), math.Inf(1), uint64(1), []string{}} {
+		if err := wb.SetCustomProperty("Bad", v); err == nil {
+			t.Errorf("accepted %T %v", v, v)
+		}
+	}
+	if err := wb.SetCustomProperty("", "x"); err == nil {
+		t.Error("accepted an empty name")
+	}
+
+	s := writeParts(t, wb).part(t, "/docProps/custom.xml")
+	wantContains(t, s,
+		`name="Small"><vt:i4>-5</vt:i4>`,
+		`name="MaxI4"><vt:i4>2147483647</vt:i4>`,
+		`name="MinI4"><vt:i4>-2147483648</vt:i4>`,
+		`name="Wide"><vt:i8>4294967295</vt:i8>`,
+		`name="Half"><vt:r8>0.5</vt:r8>`,
+		`name="Draft"><vt:bool>false</vt:bool>`,
+		`name="Local"><vt:filetime>2024-01-01T23:30:00Z</vt:filetime>`,
+		`name="Empty"><vt:lpwstr></vt:lpwstr>`)
+
+	props := regexp.MustCompile(`<property fmtid="([^"]*)" pid="(\d+)"`).FindAllStringSubmatch(s, -1)
+	if len(props) != 8 || strings.Count(s, "<property ") != 8 {
+		t.Fatalf("got %d properties, want 8", len(props))
+	}
+	for i, p := range props {
+		if p[1] != "{D5CDD505-2E9C-101B-9397-08002B2CF9AE}" || p[2] != strconv.Itoa(i+2) {
+			t.Errorf("property %d: fmtid %s pid %s", i, p[1], p[2])
+		}
+	}
+}
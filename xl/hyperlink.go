@@ -0,0 +1,37 @@
+package xl
+
+// Hyperlink is the target of a clickable cell, either an external URL or
+// a location within the workbook such as "Sheet2!A1" or a defined name.
+type Hyperlink struct {
+	URL      string
+	Location string
+	Tooltip  string
+}
+
+// SetHyperlink stores display as the cell text and links the cell to an
+// external url, e.g. "https://example.com" or "mailto:info@example.com".
+func (c *Cell) SetHyperlink(url, display string) {
+	c.SetStr(display)
+	c.hyperlink = &Hyperlink{URL: url}
+}
+
+// SetLocationLink stores display as the cell text and links the cell to a
+// location within the workbook, e.g. "'Q1 Sales'!B4".
+func (c *Cell) SetLocationLink(location, display string) {
+	c.SetStr(display)
+	c.hyperlink = &Hyperlink{Location: location}
+}
+
+// Hyperlink returns the link of the cell, or nil. The returned value can
+// be modified, e.g. to set a tooltip.
+func (c *Cell) Hyperlink() *Hyperlink {
+	return c.hyperlink
+}
+
+// sheetHyperlink is a hyperlink of a worksheet part, rid refers to the
+// relationship of an external target.
+type sheetHyperlink struct {
+	ref  string
+	rid  string
+	link *Hyperlink
+}
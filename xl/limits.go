@@ -0,0 +1,77 @@
+package xl
+
+import (
+	"fmt"
+	"unicode/utf8"
+)
+
+// Excel specification limits enforced by the writer. Exceeding them makes
+// Excel either repair the file, dropping content, or refuse to open it, so
+// Write reports an error instead, before any part is written.
+const (
+	MaxColumnNumber   = 16384   // columns A..XFD
+	MaxRowNumber      = 1048576 // rows are 1-based
+	MaxCellStyles     = 64000   // unique cell formats per workbook
+	MaxColumnWidth    = 255     // in characters
+	MaxRowHeight      = 409     // in points
+	MaxCellTextLength = 32767   // characters in a single cell
+	MaxOutlineLevel   = 7       // nesting of row and column groups
+	MaxIndent         = 250     // indentation steps of cell alignment
+	MaxHeaderLength   = 255     // characters in a page header or footer
+	MinZoomScale      = 10      // percent
+	MaxZoomScale      = 400     // percent
+)
+
+// checkSheetLimits verifies that the sheet fits within the Excel limits,
+// Write runs it for all sheets before any part is written.
+func checkSheetLimits(sh *Sheet) error {
+	if err := checkZoomScale(sh.ZoomScale); err != nil {
+		return fmt.Errorf("sheet '%s': %w", sh.Name, err)
+	}
+	if err := checkViewType(sh.ViewType); err != nil {
+		return fmt.Errorf("sheet '%s': %w", sh.Name, err)
+	}
+	if err := sh.PageSetup.validate(); err != nil {
+		return fmt.Errorf("sheet '%s': %w", sh.Name, err)
+	}
+	if err := sh.HeaderFooter.validate(); err != nil {
+		return fmt.Errorf("sheet '%s': %w", sh.Name, err)
+	}
+	if sh.DefaultColWidth > MaxColumnWidth {
+		return fmt.Errorf("sheet '%s': default column width %g exceeds the limit of %d", sh.Name, sh.DefaultColWidth, MaxColumnWidth)
+	}
+	if sh.DefaultRowHeight > MaxRowHeight {
+		return fmt.Errorf("sheet '%s': default row height %g exceeds the limit of %d points", sh.Name, sh.DefaultRowHeight, MaxRowHeight)
+	}
+	for n, c := range sh.Columns {
+		if n > MaxColumnNumber {
+			return fmt.Errorf("sheet '%s': column %d exceeds the limit of %d columns", sh.Name, n, MaxColumnNumber)
+		}
+		if c.OutlineLevel < 0 || c.OutlineLevel > MaxOutlineLevel {
+			return fmt.Errorf("sheet '%s': column %d outline level %d is out of the range 0-%d", sh.Name, n, c.OutlineLevel, MaxOutlineLevel)
+		}
+		if c.Width > MaxColumnWidth {
+			return fmt.Errorf("sheet '%s': column %d width %g exceeds the limit of %d", sh.Name, n, c.Width, MaxColumnWidth)
+		}
+	}
+	for _, r := range sh.Rows {
+		if r.rowNumber > MaxRowNumber {
+			return fmt.Errorf("sheet '%s': row %d exceeds the limit of %d rows", sh.Name, r.rowNumber, MaxRowNumber)
+		}
+		if r.OutlineLevel < 0 || r.OutlineLevel > MaxOutlineLevel {
+			return fmt.Errorf("sheet '%s': row %d outline level %d is out of the range 0-%d", sh.Name, r.rowNumber, r.OutlineLevel, MaxOutlineLevel)
+		}
+		if r.Height > MaxRowHeight {
+			return fmt.Errorf("sheet '%s': row %d height %g exceeds the limit of %d points", sh.Name, r.rowNumber, r.Height, MaxRowHeight)
+		}
+		for _, c := range r.Cells {
+			if c.columnNumber > MaxColumnNumber {
+				return fmt.Errorf("sheet '%s': cell %s exceeds the limit of %d columns", sh.Name, c.coord, MaxColumnNumber)
+			}
+			if (c.typ == CellTypeSharedString || c.typ == CellTypeInlineString) && utf8.RuneCountInString(c.v) > MaxCellTextLength {
+				return fmt.Errorf("sheet '%s': cell %s text exceeds the limit of %d characters", sh.Name, c.coord, MaxCellTextLength)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package xl
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestLimitsBeforeWrite checks that a sheet beyond the limits is reported
+// before any part is written, rather than leaving a partial package.
+func TestLimitsBeforeWrite(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		setup func(sh *Sheet)
+		want  string
+	}{
+		{"zoom", func(sh *Sheet) { sh.ZoomScale = 500 }, "zoom scale"},
+		{"view", func(sh *Sheet) { sh.ViewType = "bogus" }, "invalid view type"},
+		{"page setup", func(sh *Sheet) { sh.PageSetup.Scale = 5 }, "print scale"},
+		{"header", func(sh *Sheet) { sh.HeaderFooter.OddHeader = strings.Repeat("x", MaxHeaderLength+1) }, "header"},
+		{"row height", func(sh *Sheet) { sh.DefaultRowHeight = MaxRowHeight + 1 }, "row height"},
+	} {
+		wb := newTestSheets(t, "First", "Second")
+		tc.setup(wb.Sheets[1])
+		m := memStorage{}
+		err := NewWriter(m).Write(wb)
+		if err == nil || !strings.Contains(err.Error(), "sheet 'Second'") || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s: got %v, want an error about %s", tc.name, err, tc.want)
+		}
+		if len(m) != 0 {
+			t.Errorf("%s: %d parts written before the error", tc.name, len(m))
+		}
+	}
+}
+
+func TestRowLimit(t *testing.T) {
+	wb, sh := newTestSheet(t, "Data")
+	if _, err := sh.AddRowAt(MaxRowNumber + 1); err == nil {
+		t.Error("AddRowAt accepted a row beyond the limit")
+	}
+	if err := sh.SetNextRow(MaxRowNumber + 1); err == nil {
+		t.Error("SetNextRow accepted a row beyond the limit")
+	}
+	if _, err := sh.Cell("A1048577"); err == nil {
+		t.Error("Cell accepted a row beyond the limit")
+	}
+	if err := sh.MergeCells("A1:A1048577"); err == nil {
+		t.Error("MergeCells accepted a row beyond the limit")
+	}
+
+	// the last row is fine, the one after it is reported by Write, strict
+	// or not
+	r, err := sh.AddRowAt(MaxRowNumber)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.AddCell().SetStr("last")
+	sh.AddRow().AddCell().SetStr("beyond")
+	err = NewWriter(memStorage{}).Write(wb)
+	if err == nil || !strings.Contains(err.Error(), "exceeds the limit") {
+		t.Errorf("got %v, want a row limit error", err)
+	}
+}
+
+// TestStyleLimitsBeforeWrite checks that the styles, which are collected
+// from the cells, are checked before any part is written.
+func TestStyleLimitsBeforeWrite(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		setup func(wb *Workbook, w *Writer)
+		want  string
+	}{
+		{"styles", func(wb *Workbook, w *Writer) {
+			// pretend that the limit has been reached
+			for range MaxCellStyles {
+				w.xfs = append(w.xfs, &XF{})
+			}
+			mustCell(t, wb.Sheets[1], "B1").Font.Bold = true
+		}, "unique cell styles"},
+		{"indent", func(wb *Workbook, w *Writer) {
+			mustCell(t, wb.Sheets[1], "B1").Alignment.Indent = MaxIndent + 1
+		}, "sheet 'Second', cell B1: indent"},
+		{"column indent", func(wb *Workbook, w *Writer) {
+			wb.Sheets[1].SetColumnStyle(3, XF{Alignment: Alignment{Indent: -1}})
+		}, "sheet 'Second', column 3: indent"},
+		{"named style", func(wb *Workbook, w *Writer) {
+			if _, err := wb.AddNamedStyle("Wide", XF{Alignment: Alignment{Indent: MaxIndent + 1}}); err != nil {
+				t.Fatal(err)
+			}
+		}, "cell style 'Wide': indent"},
+	} {
+		wb := newTestSheets(t, "First", "Second")
+		m := memStorage{}
+		w := NewWriter(m)
+		tc.setup(wb, w)
+		err := w.Write(wb)
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s: got %v, want an error about %s", tc.name, err, tc.want)
+		}
+		if len(m) != 0 {
+			t.Errorf("%s: %d parts written before the error", tc.name, len(m))
+		}
+	}
+}
@@ -1,7 +1,10 @@
 package xl
 
 import (
+	"errors"
+	"fmt"
 	"hash/fnv"
+	"strings"
 )
 
 func BlobHash(blob []byte) uint64 {
@@ -9,3 +12,38 @@ func BlobHash(blob []byte) uint64 {
 	h.Write(blob)
 	return h.Sum64()
 }
+
+// pictureType returns the normalized file extension and the content type
+// of a picture, or an error if the picture can not be written.
+func pictureType(p *PictureInfo) (ext string, ctype string, err error) {
+	if p == nil {
+		return "", "", errors.New("missing picture data")
+	}
+	if len(p.Blob) == 0 {
+		return "", "", errors.New("empty picture data")
+	}
+	ext = strings.ToLower(p.Extension)
+	switch ext {
+	case ".jpg", ".jpeg":
+		return ".jpeg", "image/jpeg", nil
+	case ".png":
+		return ".png", "image/png", nil
+	}
+	return "", "", fmt.Errorf("unsupported image extension %s", ext)
+}
+
+func validatePictures(wb *Workbook) error {
+	for _, sh := range wb.Sheets {
+		for _, r := range sh.Rows {
+			for _, c := range r.Cells {
+				if c.typ != cellTypePicture {
+					continue
+				}
+				if _, _, err := pictureType(c.picture); err != nil {
+					return fmt.Errorf("sheet '%s', cell %s: %w", sh.Name, c.coord, err)
+				}
+			}
+		}
+	}
+	return nil
+}
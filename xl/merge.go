@@ -0,0 +1,270 @@
+package xl
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// cellRange is a rectangular range of cells, all numbers are 1-based and
+// inclusive.
+type cellRange struct {
+	minCol, minRow int
+	maxCol, maxRow int
+}
+
+func (r cellRange) String() string {
+	if r.minCol == r.maxCol && r.minRow == r.maxRow {
+		return CellCoordAsString(r.minCol, r.minRow)
+	}
+	return CellCoordAsString(r.minCol, r.minRow) + ":" + CellCoordAsString(r.maxCol, r.maxRow)
+}
+
+func (r cellRange) absString() string {
+	return "$" + ColumnNumberAsLetters(r.minCol) + "$" + strconv.Itoa(r.minRow) +
+		":$" + ColumnNumberAsLetters(r.maxCol) + "$" + strconv.Itoa(r.maxRow)
+}
+
+func (r cellRange) overlaps(o cellRange) bool {
+	return r.minCol <= o.maxCol && o.minCol <= r.maxCol &&
+		r.minRow <= o.maxRow && o.minRow <= r.maxRow
+}
+
+func (r cellRange) contains(col, row int) bool {
+	return col >= r.minCol && col <= r.maxCol && row >= r.minRow && row <= r.maxRow
+}
+
+// parseMergeCellRef parses an A1:B2-style range reference, the corners may
+// be specified in any order.
+func parseMergeCellRef(ref string) (cellRange, error) {
+	from, to, ok := strings.Cut(ref, ":")
+	if !ok {
+		return cellRange{}, fmt.Errorf("invalid range reference '%s'", ref)
+	}
+	c1, r1, err := parseCellRef(from)
+	if err != nil {
+		return cellRange{}, err
+	}
+	c2, r2, err := parseCellRef(to)
+	if err != nil {
+		return cellRange{}, err
+	}
+	return cellRange{
+		minCol: min(c1, c2), minRow: min(r1, r2),
+		maxCol: max(c1, c2), maxRow: max(r1, r2),
+	}, nil
+}
+
+// MergeCells merges a range of cells specified as "A1:C1". The range must
+// span at least two cells and must not overlap any previously merged range.
+func (s *Sheet) MergeCells(ref string) error {
+	r, err := parseMergeCellRef(ref)
+	if err != nil {
+		return err
+	}
+	if err = s.validateMergeRange(r); err != nil {
+		return err
+	}
+	s.mergeIndex.add(len(s.merges), r)
+	s.merges = append(s.merges, r)
+	return nil
+}
+
+// MergeAndKeepTopLeft merges a range like MergeCells and clears the values
+// of all its cells but the top-left one. Excel only shows the value of the
+// top-left cell, the others stay hidden in the file and reappear when the
+// range is unmerged. The styles of the cells are kept, e.g. for borders
+// around the merged range.
+func (s *Sheet) MergeAndKeepTopLeft(ref string) error {
+	if err := s.MergeCells(ref); err != nil {
+		return err
+	}
+	r := s.merges[len(s.merges)-1]
+	s.forEachCellIn(r, func(c *Cell) {
+		if c.columnNumber != r.minCol || c.row.rowNumber != r.minRow {
+			c.Clear()
+		}
+	})
+	return nil
+}
+
+// forEachCellIn calls fn for the existing cells within a range.
+func (s *Sheet) forEachCellIn(r cellRange, fn func(c *Cell)) {
+	i, _ := slices.BinarySearchFunc(s.Rows, r.minRow, func(row *Row, n int) int {
+		return row.rowNumber - n
+	})
+	for ; i < len(s.Rows) && s.Rows[i].rowNumber <= r.maxRow; i++ {
+		cells := s.Rows[i].Cells
+		j, _ := slices.BinarySearchFunc(cells, r.minCol, func(c *Cell, col int) int {
+			return c.columnNumber - col
+		})
+		for ; j < len(cells) && cells[j].columnNumber <= r.maxCol; j++ {
+			fn(cells[j])
+		}
+	}
+}
+
+// UnsafeMerge merges a range of cells without checking it against the
+// existing merged ranges, avoiding the cost of the overlap check for
+// generated layouts that are known to be disjoint. The range is not added
+// to the index used by that check either, so later MergeCells calls are
+// not checked against it.
+//
+// Write still rejects overlapping merged ranges through Workbook.Validate,
+// in a single pass over all ranges; set Writer.SkipValidation to skip it
+// as well. Overlapping merged ranges produce a file that Excel reports as
+// corrupt; use it only when the input is trusted.
+func (s *Sheet) UnsafeMerge(ref string) error {
+	r, err := parseMergeCellRef(ref)
+	if err != nil {
+		return err
+	}
+	s.merges = append(s.merges, r)
+	return nil
+}
+
+func (s *Sheet) validateMergeRange(r cellRange) error {
+	if r.minCol == r.maxCol && r.minRow == r.maxRow {
+		return fmt.Errorf("merged range %s must contain at least two cells", r)
+	}
+	if i := s.mergeIndex.find(r, s.merges); i >= 0 {
+		return fmt.Errorf("merged range %s overlaps %s", r, s.merges[i])
+	}
+	return nil
+}
+
+// mergeIndex speeds up overlap checks of merged ranges by bucketing them
+// into a coarse grid, so that only the ranges sharing a bucket need to be
+// compared. Ranges that span too many buckets are kept in a separate list
+// which is always scanned.
+type mergeIndex struct {
+	buckets map[[2]int][]int // bucket -> indices into Sheet.merges
+	large   []int
+}
+
+const (
+	mergeBucketCols   = 8
+	mergeBucketRows   = 32
+	mergeBucketsLimit = 64 // max buckets per range before it is considered large
+)
+
+func mergeBuckets(r cellRange) (c1, r1, c2, r2 int) {
+	return (r.minCol - 1) / mergeBucketCols, (r.minRow - 1) / mergeBucketRows,
+		(r.maxCol - 1) / mergeBucketCols, (r.maxRow - 1) / mergeBucketRows
+}
+
+func (mi *mergeIndex) add(i int, r cellRange) {
+	c1, r1, c2, r2 := mergeBuckets(r)
+	if (c2-c1+1)*(r2-r1+1) > mergeBucketsLimit {
+		mi.large = append(mi.large, i)
+		return
+	}
+	if mi.buckets == nil {
+		mi.buckets = map[[2]int][]int{}
+	}
+	for bc := c1; bc <= c2; bc++ {
+		for br := r1; br <= r2; br++ {
+			k := [2]int{bc, br}
+			mi.buckets[k] = append(mi.buckets[k], i)
+		}
+	}
+}
+
+func (mi *mergeIndex) clone() mergeIndex {
+	c := mergeIndex{large: slices.Clone(mi.large)}
+	if mi.buckets != nil {
+		c.buckets = make(map[[2]int][]int, len(mi.buckets))
+		for k, ii := range mi.buckets {
+			c.buckets[k] = slices.Clone(ii)
+		}
+	}
+	return c
+}
+
+// find returns the index of a merged range overlapping r, or -1
+func (mi *mergeIndex) find(r cellRange, merges []cellRange) int {
+	for _, i := range mi.large {
+		if merges[i].overlaps(r) {
+			return i
+		}
+	}
+	if len(mi.buckets) == 0 {
+		return -1
+	}
+	c1, r1, c2, r2 := mergeBuckets(r)
+	if (c2-c1+1)*(r2-r1+1) > len(mi.buckets) {
+		// cheaper to visit the populated buckets than the covered ones
+		for k, ii := range mi.buckets {
+			if k[0] < c1 || k[0] > c2 || k[1] < r1 || k[1] > r2 {
+				continue
+			}
+			for _, i := range ii {
+				if merges[i].overlaps(r) {
+					return i
+				}
+			}
+		}
+		return -1
+	}
+	for bc := c1; bc <= c2; bc++ {
+		for br := r1; br <= r2; br++ {
+			for _, i := range mi.buckets[[2]int{bc, br}] {
+				if merges[i].overlaps(r) {
+					return i
+				}
+			}
+		}
+	}
+	return -1
+}
+
+// validateMergesStrict rejects merged ranges that reach outside the extent
+// of the cells added to the sheet, and values hidden by merged ranges, see
+// MergeAndKeepTopLeft. The worksheet limits are checked by MergeCells.
+func (s *Sheet) validateMergesStrict() error {
+	maxCol, maxRow := s.dataExtent()
+	for _, m := range s.merges {
+		var hidden *Cell
+		s.forEachCellIn(m, func(c *Cell) {
+			if hidden == nil && c.typ != CellTypeUnset && (c.columnNumber != m.minCol || c.row.rowNumber != m.minRow) {
+				hidden = c
+			}
+		})
+		if hidden != nil {
+			return fmt.Errorf("sheet '%s': the value of cell %s is hidden by merged range %s", s.Name, hidden.coord, m)
+		}
+		if m.maxCol > maxCol || m.maxRow > maxRow {
+			return fmt.Errorf("sheet '%s': merged range %s exceeds the data extent %s",
+				s.Name, m, cellRange{1, 1, max(maxCol, 1), max(maxRow, 1)})
+		}
+	}
+	return nil
+}
+
+// validateMerges rejects overlapping merged ranges.
+func (s *Sheet) validateMerges() error {
+	// sweep the ranges top to bottom, keeping those that reach the current
+	// row
+	order := make([]int, len(s.merges))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortFunc(order, func(a, b int) int {
+		return s.merges[a].minRow - s.merges[b].minRow
+	})
+	var active []cellRange
+	for _, i := range order {
+		m := s.merges[i]
+		active = slices.DeleteFunc(active, func(a cellRange) bool {
+			return a.maxRow < m.minRow
+		})
+		for _, a := range active {
+			if a.overlaps(m) {
+				return fmt.Errorf("sheet '%s': merged ranges %s and %s overlap", s.Name, a, m)
+			}
+		}
+		active = append(active, m)
+	}
+	return nil
+}
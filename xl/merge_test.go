@@ -0,0 +1,101 @@
+package xl
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// TestMergeIndex compares the overlap checks of the index with a scan of
+// all the merged ranges, for a mix of small and large ranges.
+func TestMergeIndex(t *testing.T) {
+	_, sh := newTestSheet(t, "Data")
+	rnd := rand.New(rand.NewSource(1))
+	for n := 0; n < 2000; n++ {
+		w, h := 1+rnd.Intn(3), 1+rnd.Intn(3)
+		if n%50 == 0 {
+			w, h = 1+rnd.Intn(40), 1+rnd.Intn(400)
+		}
+		r := cellRange{minCol: 1 + rnd.Intn(100), minRow: 1 + rnd.Intn(1000)}
+		r.maxCol, r.maxRow = r.minCol+w, r.minRow+h
+		overlaps := false
+		for _, m := range sh.merges {
+			overlaps = overlaps || m.overlaps(r)
+		}
+		err := sh.MergeCells(r.String())
+		if overlaps != (err != nil) {
+			t.Fatalf("%s: got %v, overlap %v", r, err, overlaps)
+		}
+	}
+	if len(sh.merges) == 0 {
+		t.Fatal("no range merged")
+	}
+}
+
+func TestMergeCells(t *testing.T) {
+	wb, sh := newTestSheet(t, "Data")
+	for _, ref := range []string{"A1:B1", "C3:A2", "D1:D2"} {
+		if err := sh.MergeCells(ref); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for ref, want := range map[string]string{
+		"B1:B2": "merged range B1:B2 overlaps A1:B1",
+		"A4:A4": "merged range A4 must contain at least two cells",
+		"A1":    "invalid range reference 'A1'",
+	} {
+		if err := sh.MergeCells(ref); err == nil || err.Error() != want {
+			t.Errorf("%s: got %v, want %s", ref, err, want)
+		}
+	}
+	mustCell(t, sh, "A1").SetStr("x")
+	wantContains(t, writeParts(t, wb).part(t, "/xl/worksheets/sheet1.xml"),
+		`<mergeCells count="3">`, `<mergeCell ref="A1:B1"/>`, `<mergeCell ref="A2:C3"/>`, `<mergeCell ref="D1:D2"/>`)
+}
+
+func TestUnsafeMerge(t *testing.T) {
+	wb, sh := newTestSheet(t, "Data")
+	for _, ref := range []string{"A1:B2", "B2:C3"} {
+		if err := sh.UnsafeMerge(ref); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(sh.mergeIndex.buckets) != 0 || len(sh.mergeIndex.large) != 0 {
+		t.Error("UnsafeMerge added the ranges to the index")
+	}
+	// not checked against the unsafe ranges
+	if err := sh.MergeCells("A2:A3"); err != nil {
+		t.Error(err)
+	}
+
+	m := memStorage{}
+	if err := NewWriter(m).Write(wb); err == nil || !strings.Contains(err.Error(), "A1:B2 and B2:C3 overlap") {
+		t.Errorf("got %v, want an overlap error", err)
+	}
+	if len(m) != 0 {
+		t.Errorf("%d parts written before the error", len(m))
+	}
+	w := NewWriter(memStorage{})
+	w.SkipValidation = true
+	if err := w.Write(wb); err != nil {
+		t.Errorf("SkipValidation: %v", err)
+	}
+}
+
+// BenchmarkMergeCells merges 10k disjoint 1x2 ranges.
+func BenchmarkMergeCells(b *testing.B) {
+	refs := make([]string, 10000)
+	for i := range refs {
+		col, row := 1+i%50*2, 1+i/50
+		refs[i] = CellCoordAsString(col, row) + ":" + CellCoordAsString(col+1, row)
+	}
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		sh := &Sheet{}
+		for _, ref := range refs {
+			if err := sh.MergeCells(ref); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package xl
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// namedStyle is a cell style registered with Workbook.AddNamedStyle.
+type namedStyle struct {
+	name string
+	xf   XF
+}
+
+// AddNamedStyle registers a cell style under a name, which Excel lists in
+// its cell style gallery, and returns the XF to assign to the cells that
+// use it. Components set on such an XF override those of the named style,
+// e.g. a cell can use a "Currency" style with a bold font of its own.
+//
+// Names are case-insensitive and "Normal" is taken by the default style.
+func (wb *Workbook) AddNamedStyle(name string, xf XF) (XF, error) {
+	if strings.TrimSpace(name) == "" {
+		return XF{}, errors.New("empty style name")
+	}
+	if strings.EqualFold(name, "Normal") || wb.namedStyle(name) != nil {
+		return XF{}, fmt.Errorf("duplicate style name '%s'", name)
+	}
+	if xf.Style != "" {
+		return XF{}, fmt.Errorf("style '%s' can not be based on another named style", name)
+	}
+	wb.namedStyles = append(wb.namedStyles, &namedStyle{name: name, xf: xf})
+	return XF{Style: name}, nil
+}
+
+// namedStyle returns the named style with the given name, or nil.
+func (wb *Workbook) namedStyle(name string) *namedStyle {
+	for _, ns := range wb.namedStyles {
+		if strings.EqualFold(ns.name, name) {
+			return ns
+		}
+	}
+	return nil
+}
+
+// resolveStyle applies the named style that xf refers to, if any, and
+// reports names that are not registered with AddNamedStyle.
+func (wb *Workbook) resolveStyle(xf XF) (XF, error) {
+	if xf.Style == "" {
+		return xf, nil
+	}
+	ns := wb.namedStyle(xf.Style)
+	if ns == nil {
+		return xf, fmt.Errorf("unknown style '%s'", xf.Style)
+	}
+	return ns.apply(xf), nil
+}
+
+// apply fills the components that are missing from xf with those of the
+// named style.
+func (ns *namedStyle) apply(xf XF) XF {
+	if xf.NumFmt == "" {
+		xf.NumFmt = ns.xf.NumFmt
+	}
+	if xf.Font.Empty() {
+		xf.Font = ns.xf.Font
+	}
+	if xf.Fill.Empty() {
+		xf.Fill = ns.xf.Fill
+	}
+	if xf.Border.Empty() {
+		xf.Border = ns.xf.Border
+	}
+	if xf.Alignment.Empty() {
+		xf.Alignment = ns.xf.Alignment
+	}
+	if xf.Protection.Empty() {
+		xf.Protection = ns.xf.Protection
+	}
+	return xf
+}
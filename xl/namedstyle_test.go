@@ -0,0 +1,45 @@
+package xl
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNamedStyleColumn(t *testing.T) {
+	wb, sh := newTestSheet(t, "Sheet1")
+	heading, err := wb.AddNamedStyle("Heading", XF{Font: Font{Bold: true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sh.SetColumnStyle(1, heading)
+	mustCell(t, sh, "A1").SetStr("Title")
+	m := writeParts(t, wb)
+
+	// the column and the cell share the style that inherits the bold font,
+	// without overriding it
+	wantContains(t, m.part(t, "/xl/worksheets/sheet1.xml"), `<col min="1" max="1" style="1"/>`)
+	styles := m.part(t, "/xl/styles.xml")
+	wantContains(t, styles,
+		`<cellXfs count="2">`,
+		`<xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="1"/>`,
+		`<cellStyle name="Heading" xfId="1"/>`)
+	if strings.Contains(styles, "applyFont") {
+		t.Errorf("unexpected applyFont in\n%s", styles)
+	}
+}
+
+func TestNamedStyleUnknown(t *testing.T) {
+	wb, sh := newTestSheet(t, "Sheet1")
+	sh.SetColumnStyle(2, XF{Style: "NoSuch"})
+	err := NewWriter(memStorage{}).Write(wb)
+	if err == nil || !strings.Contains(err.Error(), "unknown style 'NoSuch'") {
+		t.Errorf("got %v, want an unknown style error", err)
+	}
+
+	wb, sh = newTestSheet(t, "Sheet1")
+	mustCell(t, sh, "A1").XF = XF{Style: "NoSuch"}
+	err = NewWriter(memStorage{}).Write(wb)
+	if err == nil || !strings.Contains(err.Error(), "unknown style 'NoSuch'") {
+		t.Errorf("got %v, want an unknown style error", err)
+	}
+}
@@ -0,0 +1,207 @@
+package xl
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxDefinedNameLength is the number of characters Excel accepts in a
+// defined name.
+const maxDefinedNameLength = 255
+
+// DefinedName is a named formula or range. Names with a nil Sheet are
+// global, others are only visible within their sheet.
+type DefinedName struct {
+	Name     string
+	RefersTo string // formula without the leading '=', e.g. "Sheet1!$A$1:$B$4"
+	Sheet    *Sheet
+	Hidden   bool
+}
+
+// definedNameKey identifies a defined name: the same name may be defined
+// once globally and once per sheet. Names are case-insensitive.
+type definedNameKey struct {
+	name  string
+	scope *Sheet
+}
+
+func makeDefinedNameKey(name string, scope *Sheet) definedNameKey {
+	return definedNameKey{name: strings.ToUpper(name), scope: scope}
+}
+
+// AddDefinedName adds a defined name, scoped to the given sheet or global
+// when scope is nil. A name can be defined only once within a scope, but a
+// sheet-scoped name may shadow a global one. The built-in print names are
+// derived from sheet settings and can not be added, see SetPrintArea and
+// SetPrintTitleRows.
+//
+// Names start with a letter, an underscore or a backslash, followed by
+// letters, digits, underscores, periods and backslashes, up to 255
+// characters. Names that Excel would read as a cell reference ("A1",
+// "R1C1") or a boolean are rejected.
+func (wb *Workbook) AddDefinedName(name, refersTo string, scope *Sheet) error {
+	if name == "" {
+		return errors.New("empty defined name is not allowed")
+	}
+	if strings.EqualFold(name, "_xlnm.Print_Area") || strings.EqualFold(name, "_xlnm.Print_Titles") {
+		return errors.New("defined name '" + name + "' is reserved, it is set through the sheet")
+	}
+	if err := checkDefinedName(name); err != nil {
+		return err
+	}
+	if refersTo == "" {
+		return errors.New("defined name '" + name + "' does not refer to anything")
+	}
+	if scope != nil && scope.workbook != wb {
+		return errors.New("defined name '" + name + "' is scoped to a sheet of another workbook")
+	}
+	key := makeDefinedNameKey(name, scope)
+	if _, exists := wb.definedNameIndex[key]; exists {
+		if scope != nil {
+			return errors.New("duplicate defined name '" + name + "' in sheet '" + scope.Name + "'")
+		}
+		return errors.New("duplicate global defined name '" + name + "'")
+	}
+	dn := &DefinedName{
+		Name:     name,
+		RefersTo: strings.TrimPrefix(refersTo, "="),
+		Sheet:    scope,
+	}
+	wb.definedNames = append(wb.definedNames, dn)
+	wb.definedNameIndex[key] = dn
+	return nil
+}
+
+func checkDefinedName(name string) error {
+	if utf8.RuneCountInString(name) > maxDefinedNameLength {
+		return fmt.Errorf("defined name '%s' exceeds the limit of %d characters", name, maxDefinedNameLength)
+	}
+	for i, r := range name {
+		switch {
+		case r == '_' || r == '\\' || unicode.IsLetter(r):
+		case i > 0 && (r == '.' || unicode.IsDigit(r)):
+		default:
+			return fmt.Errorf("defined name %q: character %q is not allowed", name, r)
+		}
+	}
+	if looksLikeReference(name) {
+		return errors.New("defined name '" + name + "' can be read as a cell reference")
+	}
+	return nil
+}
+
+// SetPrintArea sets the range printed by default, e.g. "A1:F40". An empty
+// ref clears the print area. It is stored as the sheet-scoped built-in name
+// _xlnm.Print_Area, which always follows the current sheet name.
+func (s *Sheet) SetPrintArea(ref string) error {
+	if ref == "" {
+		s.printArea = nil
+		return nil
+	}
+	r, err := parseMergeCellRef(ref)
+	if err != nil {
+		return err
+	}
+	s.printArea = &r
+	return nil
+}
+
+// SetPrintTitleRows sets the rows repeated at the top of every printed
+// page, e.g. 1, 1 for a header row. Passing 0, 0 clears them. Like the
+// print area, they are stored as the sheet-scoped built-in name
+// _xlnm.Print_Titles.
+func (s *Sheet) SetPrintTitleRows(startRow, endRow int) error {
+	if startRow == 0 && endRow == 0 {
+		s.printTitleRows = [2]int{}
+		return nil
+	}
+	if startRow < 1 || endRow < startRow || endRow > MaxRowNumber {
+		return fmt.Errorf("invalid print title rows %d-%d", startRow, endRow)
+	}
+	s.printTitleRows = [2]int{startRow, endRow}
+	return nil
+}
+
+// SetPrintTitleColumns sets the 1-based columns repeated at the left of
+// every printed page, see SetPrintTitleRows.
+func (s *Sheet) SetPrintTitleColumns(startCol, endCol int) error {
+	if startCol == 0 && endCol == 0 {
+		s.printTitleCols = [2]int{}
+		return nil
+	}
+	if startCol < 1 || endCol < startCol || endCol > MaxColumnNumber {
+		return fmt.Errorf("invalid print title columns %d-%d", startCol, endCol)
+	}
+	s.printTitleCols = [2]int{startCol, endCol}
+	return nil
+}
+
+// sheetDefinedNames returns the built-in names derived from sheet settings
+func (s *Sheet) sheetDefinedNames() []*DefinedName {
+	var nn []*DefinedName
+	if s.printArea != nil {
+		nn = append(nn, &DefinedName{
+			Name:     "_xlnm.Print_Area",
+			RefersTo: sheetRef(s.Name) + "!" + s.printArea.absString(),
+			Sheet:    s,
+		})
+	}
+	// columns and rows are combined into a single name, columns first
+	var titles []string
+	if c := s.printTitleCols; c[0] > 0 {
+		titles = append(titles, sheetRef(s.Name)+"!$"+ColumnNumberAsLetters(c[0])+":$"+ColumnNumberAsLetters(c[1]))
+	}
+	if r := s.printTitleRows; r[0] > 0 {
+		titles = append(titles, fmt.Sprintf("%s!$%d:$%d", sheetRef(s.Name), r[0], r[1]))
+	}
+	if len(titles) > 0 {
+		nn = append(nn, &DefinedName{
+			Name:     "_xlnm.Print_Titles",
+			RefersTo: strings.Join(titles, ","),
+			Sheet:    s,
+		})
+	}
+	return nn
+}
+
+// sheetRef returns the sheet name as used in formulas, quoted if needed
+func sheetRef(name string) string {
+	plain := !strings.ContainsAny(name[:1], "0123456789")
+	for _, r := range name {
+		if !(r == '_' || r == '.' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r > 127) {
+			plain = false
+			break
+		}
+	}
+	if plain && !looksLikeReference(name) {
+		return name
+	}
+	return "'" + strings.ReplaceAll(name, "'", "''") + "'"
+}
+
+// looksLikeReference reports whether Excel would read a name as a cell
+// reference, in A1 ("Q1", "FY2024") or R1C1 ("R1C1", "RC2", "R") style, or
+// as a boolean.
+func looksLikeReference(name string) bool {
+	s := strings.ToUpper(name)
+	if s == "TRUE" || s == "FALSE" {
+		return true
+	}
+	letters := len(s) - len(strings.TrimLeft(s, "ABCDEFGHIJKLMNOPQRSTUVWXYZ"))
+	if letters >= 1 && letters <= 3 && letters < len(s) && strings.Trim(s[letters:], "0123456789") == "" {
+		return true
+	}
+	rc := false
+	if strings.HasPrefix(s, "R") {
+		s = strings.TrimLeft(s[1:], "0123456789")
+		rc = true
+	}
+	if strings.HasPrefix(s, "C") {
+		s = strings.TrimLeft(s[1:], "0123456789")
+		rc = true
+	}
+	return rc && s == ""
+}
@@ -0,0 +1,150 @@
+package xl
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPrintTitles(t *testing.T) {
+	wb := NewWorkbook()
+	a, _ := wb.AddSheet("My Sheet")
+	b, _ := wb.AddSheet("B")
+	c, _ := wb.AddSheet("C")
+	for _, err := range []error{
+		a.SetPrintTitleRows(1, 2),
+		a.SetPrintTitleColumns(1, 2),
+		a.SetPrintArea("A1:D20"),
+		b.SetPrintTitleRows(1, 1),
+		c.SetPrintTitleColumns(3, 3),
+	} {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := b.SetPrintTitleRows(3, 1); err == nil {
+		t.Error("accepted rows 3-1")
+	}
+	if err := b.SetPrintTitleColumns(0, MaxColumnNumber+1); err == nil {
+		t.Error("accepted columns beyond the limit")
+	}
+
+	wantContains(t, writeParts(t, wb).part(t, "/xl/workbook.xml"),
+		`<definedName name="_xlnm.Print_Area" localSheetId="0">'My Sheet'!$A$1:$D$20</definedName>`,
+		`<definedName name="_xlnm.Print_Titles" localSheetId="0">'My Sheet'!$A:$B,'My Sheet'!$1:$2</definedName>`,
+		`<definedName name="_xlnm.Print_Titles" localSheetId="1">B!$1:$1</definedName>`,
+		`<definedName name="_xlnm.Print_Titles" localSheetId="2">'C'!$C:$C</definedName>`)
+
+	// clearing removes the name
+	if err := c.SetPrintTitleColumns(0, 0); err != nil {
+		t.Fatal(err)
+	}
+	if s := writeParts(t, wb).part(t, "/xl/workbook.xml"); strings.Contains(s, `localSheetId="2"`) {
+		t.Errorf("cleared print titles still written:\n%s", s)
+	}
+}
+
+func TestReservedDefinedNames(t *testing.T) {
+	wb, sh := newTestSheet(t, "Sheet1")
+	for _, name := range []string{"_xlnm.Print_Titles", "_XLNM.print_area"} {
+		if err := wb.AddDefinedName(name, "Sheet1!$1:$1", sh); err == nil {
+			t.Errorf("AddDefinedName accepted the reserved name %s", name)
+		}
+	}
+	if err := wb.AddDefinedName("Totals", "Sheet1!$A$1", sh); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestDefinedNameSyntax(t *testing.T) {
+	wb, sh := newTestSheet(t, "Sheet1")
+	for _, name := range []string{
+		"Tax Rate", "1st", ".rate", "rate!", "a\x00b", "line\nbreak",
+		"A1", "xfd1048576", "R1C1", "rc", "R", "C2", "TRUE", "false",
+		strings.Repeat("n", 256),
+	} {
+		if err := wb.AddDefinedName(name, "Sheet1!$A$1", nil); err == nil {
+			t.Errorf("AddDefinedName accepted %q", name)
+		}
+	}
+	for _, name := range []string{
+		"Rate", "_total", "\\path", "tax.rate_2", "Größe", "ABCD1", "R1C1x",
+		"_xlnm._FilterDatabase", strings.Repeat("n", 255),
+	} {
+		if err := wb.AddDefinedName(name, "Sheet1!$A$1", sh); err != nil {
+			t.Error(err)
+		}
+	}
+	wantContains(t, writeParts(t, wb).part(t, "/xl/workbook.xml"),
+		`<definedName name="Größe" localSheetId="0">Sheet1!$A$1</definedName>`)
+}
+
+// TestDefinedNamesLocalSheetID checks that user names and print areas share
+// one definedNames element, and that localSheetId is the 0-based position
+// of the scope sheet, following it when the sheets are reordered.
+func TestDefinedNamesLocalSheetID(t *testing.T) {
+	wb := NewWorkbook()
+	a, _ := wb.AddSheet("Alpha")
+	b, _ := wb.AddSheet("Beta")
+	for _, err := range []error{
+		a.SetPrintArea("A1:B10"),
+		b.SetPrintArea("C1:D5"),
+		wb.AddDefinedName("Rate", "Alpha!$A$1", nil),
+		wb.AddDefinedName("Local", "Beta!$C$1", b),
+		wb.MoveSheet("Beta", 0),
+	} {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	s := writeParts(t, wb).part(t, "/xl/workbook.xml")
+	if n := strings.Count(s, "<definedNames>"); n != 1 {
+		t.Errorf("got %d definedNames elements, want 1", n)
+	}
+	wantContains(t, s,
+		`<sheet name="Beta" sheetId="1"`,
+		`<sheet name="Alpha" sheetId="2"`,
+		`<definedName name="Rate">Alpha!$A$1</definedName>`,
+		`<definedName name="Local" localSheetId="0">Beta!$C$1</definedName>`,
+		`<definedName name="_xlnm.Print_Area" localSheetId="0">Beta!$C$1:$D$5</definedName>`,
+		`<definedName name="_xlnm.Print_Area" localSheetId="1">Alpha!$A$1:$B$10</definedName>`)
+}
+
+func TestSheetRef(t *testing.T) {
+	for name, want := range map[string]string{
+		"Data":       "Data",
+		"Sales_2024": "Sales_2024",
+		"Q1X":        "Q1X",
+		"ABCD12":     "ABCD12",
+		"Rx":         "Rx",
+		"My Sheet":   "'My Sheet'",
+		"1st":        "'1st'",
+		"It's":       "'It''s'",
+		"Q1":         "'Q1'",
+		"FY2024":     "'FY2024'",
+		"AB12":       "'AB12'",
+		"xfd1":       "'xfd1'",
+		"R1C1":       "'R1C1'",
+		"RC2":        "'RC2'",
+		"R":          "'R'",
+		"c":          "'c'",
+		"R12":        "'R12'",
+		"True":       "'True'",
+		"FALSE":      "'FALSE'",
+	} {
+		if got := sheetRef(name); got != want {
+			t.Errorf("sheetRef(%s) = %s, want %s", name, got, want)
+		}
+	}
+
+	wb := NewWorkbook()
+	sh, _ := wb.AddSheet("Q1")
+	if err := sh.SetPrintArea("A1:C3"); err != nil {
+		t.Fatal(err)
+	}
+	if err := sh.SetPrintTitleRows(1, 1); err != nil {
+		t.Fatal(err)
+	}
+	wantContains(t, writeParts(t, wb).part(t, "/xl/workbook.xml"),
+		`<definedName name="_xlnm.Print_Area" localSheetId="0">'Q1'!$A$1:$C$3</definedName>`,
+		`<definedName name="_xlnm.Print_Titles" localSheetId="0">'Q1'!$1:$1</definedName>`)
+}
@@ -0,0 +1,133 @@
+package xl
+
+import "strings"
+
+// builtinNumFmts maps the codes of the built-in number formats to their
+// reserved ids, these are not written to the numFmts section. Ids 5-8 and
+// 23-36 are locale dependent currency and date formats and have no fixed
+// code.
+var builtinNumFmts = map[string]int{
+	"General":                  0,
+	"0":                        1,
+	"0.00":                     2,
+	"#,##0":                    3,
+	"#,##0.00":                 4,
+	"0%":                       9,
+	"0.00%":                    10,
+	"0.00E+00":                 11,
+	"# ?/?":                    12,
+	"# ??/??":                  13,
+	DateFormat:                 14,
+	"d-mmm-yy":                 15,
+	"d-mmm":                    16,
+	"mmm-yy":                   17,
+	"h:mm AM/PM":               18,
+	"h:mm:ss AM/PM":            19,
+	"h:mm":                     20,
+	"h:mm:ss":                  21,
+	DateTimeFormat:             22,
+	"#,##0 ;(#,##0)":           37,
+	"#,##0 ;[Red](#,##0)":      38,
+	"#,##0.00;(#,##0.00)":      39,
+	"#,##0.00;[Red](#,##0.00)": 40,
+	"mm:ss":                    45,
+	"[h]:mm:ss":                46,
+	"mmss.0":                   47,
+	"##0.0E+0":                 48,
+	"@":                        49,
+}
+
+// MaxNumFmtDecimals is the largest number of decimal places in a format
+// code accepted by Excel, the helpers below clamp to 0-MaxNumFmtDecimals.
+const MaxNumFmtDecimals = 30
+
+// numFmtDecimals returns "0" followed by the given number of decimal
+// places.
+func numFmtDecimals(decimals int) string {
+	decimals = min(max(decimals, 0), MaxNumFmtDecimals)
+	if decimals == 0 {
+		return "0"
+	}
+	return "0." + strings.Repeat("0", decimals)
+}
+
+// NumFmtThousands returns the format code of a number with a thousands
+// separator, e.g. "#,##0.00" for 2 decimals. The decimals are clamped to
+// 0-MaxNumFmtDecimals.
+func NumFmtThousands(decimals int) string {
+	return "#,##" + numFmtDecimals(decimals)
+}
+
+// NumFmtPercent returns the format code of a percentage, e.g. "0.0%" for 1
+// decimal, with the decimals clamped to 0-MaxNumFmtDecimals. The cell holds
+// the fraction, 0.25 is displayed as 25%.
+func NumFmtPercent(decimals int) string {
+	return numFmtDecimals(decimals) + "%"
+}
+
+// CurrencyFormat describes a currency number format, see Code.
+type CurrencyFormat struct {
+	Symbol      string // e.g. "$" or "€"
+	Decimals    int    // clamped to 0-MaxNumFmtDecimals
+	SymbolAfter bool   // "1,234.00 €" rather than "€1,234.00"
+	NegativeRed bool   // negative amounts are shown in red
+}
+
+// Code returns the format code, e.g. `"$"#,##0.00` or
+// `#,##0.00 "€";[Red]-#,##0.00 "€"` with SymbolAfter and NegativeRed.
+func (cf CurrencyFormat) Code() string {
+	// the symbol is quoted as literal text, quotes can not appear inside
+	// and are written as escaped characters between the quoted runs
+	sym := `"` + strings.ReplaceAll(cf.Symbol, `"`, `"\""`) + `"`
+	code := NumFmtThousands(cf.Decimals)
+	switch {
+	case cf.Symbol == "":
+	case cf.SymbolAfter:
+		code += " " + sym
+	default:
+		code = sym + code
+	}
+	if cf.NegativeRed {
+		code += ";[Red]-" + code
+	}
+	return code
+}
+
+// NumFmtCurrency returns the format code of an amount with the currency
+// symbol in front, e.g. `"$"#,##0.00`, with the decimals clamped to
+// 0-MaxNumFmtDecimals; use CurrencyFormat for the other variants.
+func NumFmtCurrency(symbol string, decimals int) string {
+	return CurrencyFormat{Symbol: symbol, Decimals: decimals}.Code()
+}
+
+// DateStyle selects a date format for NumFmtDate.
+type DateStyle int
+
+const (
+	DateShort     DateStyle = iota // DateFormat, follows the system locale
+	DateMedium                     // 5-Mar-24
+	DateLong                       // March 5, 2024
+	DateISO                        // 2024-03-05
+	DateTimeShort                  // DateTimeFormat, follows the system locale
+	DateTimeISO                    // 2024-03-05 14:30:00
+)
+
+// NumFmtDate returns the format code of a date style, or "" for an unknown
+// style, which leaves a cell without a number format.
+func NumFmtDate(style DateStyle) string {
+	switch style {
+	case DateMedium:
+		return "d-mmm-yy"
+	case DateLong:
+		return "mmmm d, yyyy"
+	case DateISO:
+		return "yyyy-mm-dd"
+	case DateTimeShort:
+		return DateTimeFormat
+	case DateTimeISO:
+		return "yyyy-mm-dd hh:mm:ss"
+	case DateShort:
+		return DateFormat
+	}
+	return ""
+}
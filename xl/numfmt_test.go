@@ -0,0 +1,39 @@
+package xl
+
+import "testing"
+
+func TestNumFmtHelpers(t *testing.T) {
+	for _, tc := range []struct {
+		got, want string
+	}{
+		{NumFmtThousands(0), "#,##0"},
+		{NumFmtThousands(2), "#,##0.00"},
+		{NumFmtThousands(-1), "#,##0"},
+		{NumFmtThousands(MaxNumFmtDecimals + 5), NumFmtThousands(MaxNumFmtDecimals)},
+		{NumFmtPercent(0), "0%"},
+		{NumFmtPercent(1), "0.0%"},
+		{NumFmtCurrency("$", 2), `"$"#,##0.00`},
+		{NumFmtCurrency("", 0), "#,##0"},
+		{CurrencyFormat{Symbol: "$", Decimals: 2, NegativeRed: true}.Code(), `"$"#,##0.00;[Red]-"$"#,##0.00`},
+		{CurrencyFormat{Symbol: "€", Decimals: 2, SymbolAfter: true}.Code(), `#,##0.00 "€"`},
+		{CurrencyFormat{Symbol: "€", Decimals: 2, SymbolAfter: true, NegativeRed: true}.Code(), `#,##0.00 "€";[Red]-#,##0.00 "€"`},
+		{CurrencyFormat{Symbol: `a"b`}.Code(), `"a"\""b"#,##0`},
+		{NumFmtDate(DateShort), DateFormat},
+		{NumFmtDate(DateISO), "yyyy-mm-dd"},
+		{NumFmtDate(DateTimeISO), "yyyy-mm-dd hh:mm:ss"},
+		{NumFmtDate(DateStyle(99)), ""},
+	} {
+		if tc.got != tc.want {
+			t.Errorf("got %s, want %s", tc.got, tc.want)
+		}
+	}
+}
+
+func TestNumFmtCurrencyPart(t *testing.T) {
+	wb, sh := newTestSheet(t, "Data")
+	c := mustCell(t, sh, "A1")
+	c.SetFloat(-12.5)
+	c.NumFmt = CurrencyFormat{Symbol: "$", Decimals: 2, NegativeRed: true}.Code()
+	wantContains(t, writeParts(t, wb).part(t, "/xl/styles.xml"),
+		`<numFmt numFmtId="164" formatCode="&quot;$&quot;#,##0.00;[Red]-&quot;$&quot;#,##0.00"/>`)
+}
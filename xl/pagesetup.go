@@ -0,0 +1,175 @@
+package xl
+
+import (
+	"fmt"
+	"unicode/utf8"
+)
+
+// PaperSize is the paper size code used for printing, as defined by
+// ECMA-376. Zero leaves the printer default.
+type PaperSize int
+
+// Paper sizes; dimensions are width x height.
+const (
+	PaperLetter                 PaperSize = 1  // 8.5 x 11 in
+	PaperLetterSmall            PaperSize = 2  // 8.5 x 11 in
+	PaperTabloid                PaperSize = 3  // 11 x 17 in
+	PaperLedger                 PaperSize = 4  // 17 x 11 in
+	PaperLegal                  PaperSize = 5  // 8.5 x 14 in
+	PaperStatement              PaperSize = 6  // 5.5 x 8.5 in
+	PaperExecutive              PaperSize = 7  // 7.25 x 10.5 in
+	PaperA3                     PaperSize = 8  // 297 x 420 mm
+	PaperA4                     PaperSize = 9  // 210 x 297 mm
+	PaperA4Small                PaperSize = 10 // 210 x 297 mm
+	PaperA5                     PaperSize = 11 // 148 x 210 mm
+	PaperB4                     PaperSize = 12 // 250 x 353 mm
+	PaperB5                     PaperSize = 13 // 176 x 250 mm
+	PaperFolio                  PaperSize = 14 // 8.5 x 13 in
+	PaperQuarto                 PaperSize = 15 // 215 x 275 mm
+	PaperStandard10x14          PaperSize = 16 // 10 x 14 in
+	PaperStandard11x17          PaperSize = 17 // 11 x 17 in
+	PaperNote                   PaperSize = 18 // 8.5 x 11 in
+	PaperEnvelope9              PaperSize = 19 // 3.875 x 8.875 in
+	PaperEnvelope10             PaperSize = 20 // 4.125 x 9.5 in
+	PaperEnvelope11             PaperSize = 21 // 4.5 x 10.375 in
+	PaperEnvelope12             PaperSize = 22 // 4.75 x 11 in
+	PaperEnvelope14             PaperSize = 23 // 5 x 11.5 in
+	PaperC                      PaperSize = 24 // 17 x 22 in
+	PaperD                      PaperSize = 25 // 22 x 34 in
+	PaperE                      PaperSize = 26 // 34 x 44 in
+	PaperEnvelopeDL             PaperSize = 27 // 110 x 220 mm
+	PaperEnvelopeC5             PaperSize = 28 // 162 x 229 mm
+	PaperEnvelopeC3             PaperSize = 29 // 324 x 458 mm
+	PaperEnvelopeC4             PaperSize = 30 // 229 x 324 mm
+	PaperEnvelopeC6             PaperSize = 31 // 114 x 162 mm
+	PaperEnvelopeC65            PaperSize = 32 // 114 x 229 mm
+	PaperEnvelopeB4             PaperSize = 33 // 250 x 353 mm
+	PaperEnvelopeB5             PaperSize = 34 // 176 x 250 mm
+	PaperEnvelopeB6             PaperSize = 35 // 176 x 125 mm
+	PaperEnvelopeItaly          PaperSize = 36 // 110 x 230 mm
+	PaperEnvelopeMonarch        PaperSize = 37 // 3.875 x 7.5 in
+	PaperEnvelope6_3_4          PaperSize = 38 // 3.625 x 6.5 in
+	PaperUSStandardFanfold      PaperSize = 39 // 14.875 x 11 in
+	PaperGermanStandardFanfold  PaperSize = 40 // 8.5 x 12 in
+	PaperGermanLegalFanfold     PaperSize = 41 // 8.5 x 13 in
+	PaperISOB4                  PaperSize = 42 // 250 x 353 mm
+	PaperJapaneseDoublePostcard PaperSize = 43 // 200 x 148 mm
+	PaperStandard9x11           PaperSize = 44 // 9 x 11 in
+	PaperStandard10x11          PaperSize = 45 // 10 x 11 in
+	PaperStandard15x11          PaperSize = 46 // 15 x 11 in
+	PaperEnvelopeInvite         PaperSize = 47 // 220 x 220 mm
+	PaperLetterExtra            PaperSize = 50 // 9.275 x 12 in
+	PaperLegalExtra             PaperSize = 51 // 9.275 x 15 in
+	PaperTabloidExtra           PaperSize = 52 // 11.69 x 18 in
+	PaperA4Extra                PaperSize = 53 // 236 x 322 mm
+	PaperLetterTransverse       PaperSize = 54 // 8.275 x 11 in
+	PaperA4Transverse           PaperSize = 55 // 210 x 297 mm
+	PaperLetterExtraTransverse  PaperSize = 56 // 9.275 x 12 in
+	PaperSuperA                 PaperSize = 57 // 227 x 356 mm
+	PaperSuperB                 PaperSize = 58 // 305 x 487 mm
+	PaperLetterPlus             PaperSize = 59 // 8.5 x 12.69 in
+	PaperA4Plus                 PaperSize = 60 // 210 x 330 mm
+	PaperA5Transverse           PaperSize = 61 // 148 x 210 mm
+	PaperJISB5Transverse        PaperSize = 62 // 182 x 257 mm
+	PaperA3Extra                PaperSize = 63 // 322 x 445 mm
+	PaperA5Extra                PaperSize = 64 // 174 x 235 mm
+	PaperISOB5Extra             PaperSize = 65 // 201 x 276 mm
+	PaperA2                     PaperSize = 66 // 420 x 594 mm
+	PaperA3Transverse           PaperSize = 67 // 297 x 420 mm
+	PaperA3ExtraTransverse      PaperSize = 68 // 322 x 445 mm
+)
+
+// validate reports an error for codes that are not in the ECMA-376 table,
+// 48 and 49 are unassigned.
+func (p PaperSize) validate() error {
+	if p < 0 || p > PaperA3ExtraTransverse || p == 48 || p == 49 {
+		return fmt.Errorf("unknown paper size %d", p)
+	}
+	return nil
+}
+
+// Page orientations.
+const (
+	OrientationPortrait  = "portrait"
+	OrientationLandscape = "landscape"
+)
+
+// PageSetup controls how a sheet is printed, zero values leave the printer
+// defaults. Scale and the fit-to-page options are mutually exclusive: when
+// FitToWidth or FitToHeight is set, the sheet is shrunk to fit the given
+// number of pages across and down, where zero for the other dimension
+// means as many pages as needed.
+type PageSetup struct {
+	Orientation string    // OrientationPortrait or OrientationLandscape
+	PaperSize   PaperSize // see the Paper constants
+	Scale       int       // percent, 10-400
+	FitToWidth  int
+	FitToHeight int
+}
+
+func (ps *PageSetup) empty() bool {
+	return *ps == PageSetup{}
+}
+
+func (ps *PageSetup) fitToPage() bool {
+	return ps.FitToWidth > 0 || ps.FitToHeight > 0
+}
+
+func (ps *PageSetup) validate() error {
+	switch ps.Orientation {
+	case "", OrientationPortrait, OrientationLandscape:
+	default:
+		return fmt.Errorf("invalid page orientation '%s'", ps.Orientation)
+	}
+	if err := ps.PaperSize.validate(); err != nil {
+		return err
+	}
+	if ps.Scale != 0 && (ps.Scale < 10 || ps.Scale > 400) {
+		return fmt.Errorf("print scale %d%% is out of the range 10-400%%", ps.Scale)
+	}
+	if ps.FitToWidth < 0 || ps.FitToHeight < 0 {
+		return fmt.Errorf("invalid fit to page size %dx%d", ps.FitToWidth, ps.FitToHeight)
+	}
+	if ps.Scale != 0 && ps.fitToPage() {
+		return fmt.Errorf("print scale conflicts with fit to page")
+	}
+	return nil
+}
+
+// HeaderFooter holds the texts printed at the top and bottom of the pages.
+// They may contain the field codes of Excel, which are written as is: &L,
+// &C and &R start the left, center and right sections, &P is the page
+// number, &N the number of pages, &D the date, &T the time, &A the sheet
+// name and &F the file name; a literal ampersand is written as &&.
+//
+// The odd texts apply to all pages unless the even or first page texts are
+// set, which then replace them on even pages and on the first page.
+type HeaderFooter struct {
+	OddHeader   string
+	OddFooter   string
+	EvenHeader  string
+	EvenFooter  string
+	FirstHeader string
+	FirstFooter string
+}
+
+func (hf *HeaderFooter) empty() bool {
+	return *hf == HeaderFooter{}
+}
+
+func (hf *HeaderFooter) differentOddEven() bool {
+	return hf.EvenHeader != "" || hf.EvenFooter != ""
+}
+
+func (hf *HeaderFooter) differentFirst() bool {
+	return hf.FirstHeader != "" || hf.FirstFooter != ""
+}
+
+func (hf *HeaderFooter) validate() error {
+	for _, s := range []string{hf.OddHeader, hf.OddFooter, hf.EvenHeader, hf.EvenFooter, hf.FirstHeader, hf.FirstFooter} {
+		if utf8.RuneCountInString(s) > MaxHeaderLength {
+			return fmt.Errorf("header or footer text is longer than %d characters", MaxHeaderLength)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package xl
+
+import (
+	"fmt"
+	"math"
+)
+
+// pane describes the frozen or split panes of a sheet view.
+type pane struct {
+	frozen bool
+
+	// for frozen panes, the number of columns and rows that stay visible,
+	// for split panes, the position of the split bars in twips (1/20 pt)
+	xSplit float64
+	ySplit float64
+}
+
+// FreezePanes freezes the given number of leftmost columns and topmost
+// rows, so that they stay visible while the rest of the sheet scrolls.
+// Passing zero for both removes the panes.
+func (s *Sheet) FreezePanes(cols, rows int) error {
+	p, err := newFrozenPane(cols, rows)
+	if err != nil {
+		return err
+	}
+	s.pane = p
+	return nil
+}
+
+// FreezeFirstRow keeps the top row, typically the header, visible.
+func (s *Sheet) FreezeFirstRow() {
+	s.pane = &pane{frozen: true, ySplit: 1}
+}
+
+// FreezeFirstColumn keeps the leftmost column visible.
+func (s *Sheet) FreezeFirstColumn() {
+	s.pane = &pane{frozen: true, xSplit: 1}
+}
+
+// newFrozenPane returns the frozen pane for the given number of columns and
+// rows, nil if both are zero.
+func newFrozenPane(cols, rows int) (*pane, error) {
+	if cols < 0 || cols >= MaxColumnNumber {
+		return nil, fmt.Errorf("invalid number of frozen columns: %d", cols)
+	}
+	if rows < 0 || rows >= MaxRowNumber {
+		return nil, fmt.Errorf("invalid number of frozen rows: %d", rows)
+	}
+	if cols == 0 && rows == 0 {
+		return nil, nil
+	}
+	return &pane{frozen: true, xSplit: float64(cols), ySplit: float64(rows)}, nil
+}
+
+// SplitPanes splits the window into independently scrolling panes. Unlike
+// FreezePanes, the positions of the split bars are not cell counts but
+// distances from the top-left corner of the grid, measured in points
+// (1/72 inch). Use zero for x or y to split in one direction only, zero for
+// both removes the panes.
+func (s *Sheet) SplitPanes(xPoints, yPoints float64) error {
+	if !(xPoints >= 0) || math.IsInf(xPoints, 0) {
+		return fmt.Errorf("invalid horizontal split position: %v", xPoints)
+	}
+	if !(yPoints >= 0) || math.IsInf(yPoints, 0) {
+		return fmt.Errorf("invalid vertical split position: %v", yPoints)
+	}
+	if xPoints == 0 && yPoints == 0 {
+		s.pane = nil
+		return nil
+	}
+	s.pane = &pane{xSplit: xPoints * 20, ySplit: yPoints * 20}
+	return nil
+}
+
+// activePane returns the pane that receives the selection, which is the
+// bottom-right-most one.
+func (p *pane) activePane() string {
+	switch {
+	case p.xSplit > 0 && p.ySplit > 0:
+		return "bottomRight"
+	case p.ySplit > 0:
+		return "bottomLeft"
+	default:
+		return "topRight"
+	}
+}
+
+// topLeftCell returns the first unfrozen cell, it is only meaningful for
+// frozen panes.
+func (p *pane) topLeftCell() string {
+	return CellCoordAsString(int(p.xSplit)+1, int(p.ySplit)+1)
+}
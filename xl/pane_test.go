@@ -0,0 +1,53 @@
+package xl
+
+import (
+	"math"
+	"testing"
+)
+
+func TestPanes(t *testing.T) {
+	wb := NewWorkbook()
+	frozen, _ := wb.AddSheet("Frozen")
+	split, _ := wb.AddSheet("Split")
+	splitY, _ := wb.AddSheet("SplitY")
+	for _, err := range []error{
+		frozen.FreezePanes(2, 1),
+		split.SplitPanes(72, 14.5),
+		splitY.SplitPanes(0, 30),
+	} {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, err := range []error{
+		frozen.FreezePanes(-1, 0),
+		frozen.FreezePanes(0, MaxRowNumber),
+		split.SplitPanes(math.NaN(), 0),
+		split.SplitPanes(0, math.Inf(1)),
+		split.SplitPanes(-1, 0),
+	} {
+		if err == nil {
+			t.Error("invalid panes accepted")
+		}
+	}
+	m := writeParts(t, wb)
+	// frozen panes count cells and name the first scrolling cell, split
+	// panes are in twips and have no state
+	wantContains(t, m.part(t, "/xl/worksheets/sheet1.xml"),
+		`<pane xSplit="2" ySplit="1" topLeftCell="C2" activePane="bottomRight" state="frozen"/>`)
+	wantContains(t, m.part(t, "/xl/worksheets/sheet2.xml"),
+		`<pane xSplit="1440" ySplit="290" activePane="bottomRight"/>`)
+	wantContains(t, m.part(t, "/xl/worksheets/sheet3.xml"),
+		`<pane ySplit="600" activePane="bottomLeft"/>`)
+
+	// zero removes the panes
+	if err := frozen.FreezePanes(0, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := split.SplitPanes(0, 0); err != nil {
+		t.Fatal(err)
+	}
+	if frozen.pane != nil || split.pane != nil {
+		t.Error("panes not removed")
+	}
+}
@@ -0,0 +1,132 @@
+package xl
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// TestPictureValueMetadata follows the chain from the vm attribute of each
+// picture cell to the media part it shows: valueMetadata, futureMetadata,
+// the rich value and its relationship.
+func TestPictureValueMetadata(t *testing.T) {
+	wb, sh := newTestSheet(t, "Data")
+	mustCell(t, sh, "A1").SetPicture(&PictureInfo{Extension: ".png", Blob: []byte("logo")})
+	mustCell(t, sh, "B1").SetPicture(&PictureInfo{Extension: ".PNG", Blob: []byte("logo")})
+	mustCell(t, sh, "C1").SetPicture(&PictureInfo{Extension: ".jpg", Blob: []byte("photo")})
+	m := writeParts(t, wb)
+
+	media := 0
+	for name := range m {
+		if strings.HasPrefix(name, "/xl/media/") {
+			media++
+		}
+	}
+	if media != 2 {
+		t.Errorf("got %d media parts, want 2", media)
+	}
+
+	all := func(part, expr string) []int {
+		var ii []int
+		for _, sm := range regexp.MustCompile(expr).FindAllStringSubmatch(m.part(t, part), -1) {
+			i, _ := strconv.Atoi(sm[1])
+			ii = append(ii, i)
+		}
+		return ii
+	}
+	rc := all("/xl/metadata.xml", `<rc t="1" v="(\d+)"/>`)
+	rvb := all("/xl/metadata.xml", `<xlrd:rvb i="(\d+)"/>`)
+	rv := all("/xl/richData/rdrichvalue.xml", `<rv s="0"><v>(\d+)</v>`)
+	rels := regexp.MustCompile(`<rel r:id="(rId\d+)"/>`).FindAllStringSubmatch(m.part(t, "/xl/richData/richValueRel.xml"), -1)
+	if len(rc) != 2 || len(rvb) != 2 || len(rv) != 2 || len(rels) != 2 {
+		t.Fatalf("got %d valueMetadata, %d futureMetadata, %d rich values, %d rels, want 2 each", len(rc), len(rvb), len(rv), len(rels))
+	}
+	relsPart := m.part(t, "/xl/richData/_rels/richValueRel.xml.rels")
+
+	sheet := m.part(t, "/xl/worksheets/sheet1.xml")
+	for ref, ext := range map[string]string{"A1": ".png", "B1": ".png", "C1": ".jpeg"} {
+		sm := regexp.MustCompile(`<c r="` + ref + `" vm="(\d+)" t="e"><v>#VALUE!</v></c>`).FindStringSubmatch(sheet)
+		if sm == nil {
+			t.Errorf("%s: missing picture cell", ref)
+			continue
+		}
+		vm, _ := strconv.Atoi(sm[1])
+		if vm < 1 || vm > len(rc) {
+			t.Errorf("%s: vm %d is out of range", ref, vm)
+			continue
+		}
+		rid := rels[rv[rvb[rc[vm-1]]]][1]
+		target := regexp.MustCompile(`Id="` + rid + `" [^>]*Target="([^"]+)"`).FindStringSubmatch(relsPart)
+		if target == nil || !strings.HasSuffix(target[1], ext) {
+			t.Errorf("%s: vm %d leads to %v, want a %s image", ref, vm, target, ext)
+		}
+	}
+}
+
+// TestPictureRelsOrder uses enough pictures for the relationship ids to
+// reach two digits, "rId10" must follow "rId9" rather than "rId1".
+func TestPictureRelsOrder(t *testing.T) {
+	wb, sh := newTestSheet(t, "Data")
+	const n = 12
+	for i := 1; i <= n; i++ {
+		mustCell(t, sh, fmt.Sprintf("A%d", i)).SetPicture(&PictureInfo{Extension: ".png", Blob: []byte(fmt.Sprint("picture ", i))})
+	}
+	m := writeParts(t, wb)
+	ids := regexp.MustCompile(`Id="(rId\d+)"`).FindAllStringSubmatch(m.part(t, "/xl/richData/_rels/richValueRel.xml.rels"), -1)
+	rels := regexp.MustCompile(`<rel r:id="(rId\d+)"/>`).FindAllStringSubmatch(m.part(t, "/xl/richData/richValueRel.xml"), -1)
+	if len(ids) != n || len(rels) != n {
+		t.Fatalf("got %d relationships and %d rels, want %d", len(ids), len(rels), n)
+	}
+	for i := range ids {
+		want := fmt.Sprintf("rId%d", i+1)
+		if ids[i][1] != want || rels[i][1] != want {
+			t.Errorf("relationship %d: got %s and %s, want %s", i, ids[i][1], rels[i][1], want)
+		}
+	}
+}
+
+func TestCompareRelIDs(t *testing.T) {
+	for _, tc := range []struct {
+		a, b string
+		want int
+	}{
+		{"rId2", "rId10", -1},
+		{"rId10", "rId9", 1},
+		{"rId3", "rId3", 0},
+		{"rIdX", "rId1", 1}, // lexical without a number
+	} {
+		got := compareRelIDs(tc.a, tc.b)
+		if got < 0 && tc.want >= 0 || got > 0 && tc.want <= 0 || got == 0 && tc.want != 0 {
+			t.Errorf("compareRelIDs(%s, %s) = %d, want the sign of %d", tc.a, tc.b, got, tc.want)
+		}
+	}
+}
+
+// TestValueMetadata checks that the metadata part follows the registered
+// valueMetadata entries rather than the media.
+func TestValueMetadata(t *testing.T) {
+	wb, sh := newTestSheet(t, "Data")
+	mustCell(t, sh, "A1").SetStr("x")
+	m := writeParts(t, wb)
+	if _, ok := m["/xl/metadata.xml"]; ok {
+		t.Error("metadata part written without value metadata")
+	}
+	if s := m.part(t, "/xl/worksheets/sheet1.xml"); strings.Contains(s, "vm=") {
+		t.Errorf("vm written without value metadata:\n%s", s)
+	}
+
+	w := NewWriter(memStorage{})
+	if vm := w.addValueMetadata(valueMetadata{richValue: 3}); vm != 1 {
+		t.Errorf("first entry: got vm %d, want 1", vm)
+	}
+	if vm := w.addValueMetadata(valueMetadata{richValue: 0}); vm != 2 {
+		t.Errorf("second entry: got vm %d, want 2", vm)
+	}
+	c := &Cell{}
+	c.SetFloat(1)
+	if vm, err := w.cellValueMetadata(c); vm != 0 || err != nil {
+		t.Errorf("number cell: got vm %d, %v", vm, err)
+	}
+}
@@ -0,0 +1,87 @@
+package xl
+
+import "fmt"
+
+// SheetProtection holds the options of a protected sheet, see Sheet.Protect.
+// The flags allow actions that are otherwise blocked; with the zero value
+// the user can only select and edit the cells that are not locked, see
+// CellProtection.
+type SheetProtection struct {
+	// Password is required to unprotect the sheet, empty for none. It is
+	// stored as a 16-bit hash, which keeps casual users from changing the
+	// sheet but is trivial to break: this is obfuscation, not encryption.
+	Password string
+
+	SelectLockedCells bool
+	FormatCells       bool
+	FormatColumns     bool
+	FormatRows        bool
+	InsertColumns     bool
+	InsertRows        bool
+	InsertHyperlinks  bool
+	DeleteColumns     bool
+	DeleteRows        bool
+	Sort              bool
+	AutoFilter        bool
+	EditObjects       bool
+	EditScenarios     bool
+}
+
+// CellProtection controls the behavior of a cell on a protected sheet. All
+// cells are locked by default, Unlocked allows editing the cell, Hidden
+// hides its formula.
+type CellProtection struct {
+	Unlocked bool
+	Hidden   bool
+}
+
+func (p *CellProtection) Empty() bool {
+	return *p == CellProtection{}
+}
+
+// Protect protects the sheet from changes, replacing the previous options.
+func (s *Sheet) Protect(opts SheetProtection) error {
+	if _, err := passwordHash(opts.Password); err != nil {
+		return err
+	}
+	s.protection = &opts
+	return nil
+}
+
+// Unprotect removes the protection of the sheet.
+func (s *Sheet) Unprotect() {
+	s.protection = nil
+}
+
+// Protected reports whether the sheet is protected.
+func (s *Sheet) Protected() bool {
+	return s.protection != nil
+}
+
+// passwordHash returns the legacy password verifier of ECMA-376 Part 4,
+// computed over the single-byte characters of the password.
+func passwordHash(password string) (uint16, error) {
+	if password == "" {
+		return 0, nil
+	}
+	var b []byte
+	for _, r := range password {
+		if r > 0xff {
+			return 0, fmt.Errorf("invalid character %q in password", r)
+		}
+		b = append(b, byte(r))
+	}
+	if len(b) > 255 {
+		return 0, fmt.Errorf("password is longer than 255 characters")
+	}
+	var h uint16
+	for i := len(b) - 1; i >= 0; i-- {
+		h = rotateHash(h) ^ uint16(b[i])
+	}
+	return rotateHash(h) ^ uint16(len(b)) ^ 0xce4b, nil
+}
+
+// rotateHash rotates the 15 low bits of h left by one.
+func rotateHash(h uint16) uint16 {
+	return (h>>14)&1 | (h<<1)&0x7fff
+}
@@ -0,0 +1,35 @@
+package xl
+
+import "testing"
+
+func TestCellFormatAlignmentAndProtection(t *testing.T) {
+	wb, sh := newTestSheet(t, "Sheet1")
+	c := mustCell(t, sh, "A1")
+	c.SetStr("x")
+	c.XF = XF{
+		Alignment:  Alignment{Horizontal: "center"},
+		Protection: CellProtection{Unlocked: true},
+	}
+	styles := writeParts(t, wb).part(t, "/xl/styles.xml")
+	// the apply flags are attributes of xf, they must precede its children
+	wantContains(t, styles,
+		`applyAlignment="1" applyProtection="1"><alignment horizontal="center"/><protection locked="0"/></xf>`)
+}
+
+func TestPasswordHash(t *testing.T) {
+	for _, tc := range []struct {
+		password string
+		want     uint16
+	}{
+		{"", 0},
+		{"secret", 0xDAA7},
+	} {
+		got, err := passwordHash(tc.password)
+		if err != nil || got != tc.want {
+			t.Errorf("passwordHash(%q) = %04X, %v; want %04X", tc.password, got, err, tc.want)
+		}
+	}
+	if _, err := passwordHash("пароль"); err == nil {
+		t.Error("expected an error for characters outside Latin-1")
+	}
+}
@@ -0,0 +1,377 @@
+package xl
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Read parses an .xlsx file into a new workbook, so that an existing file
+// can be inspected or edited and written back.
+//
+// Only the sheets and the values of their cells are read: numbers,
+// strings, booleans, errors and dates; formulas are replaced with their
+// cached results, as the package does not write formulas. Row heights, the
+// outline grouping of rows and the visibility of rows and sheets are kept,
+// while styles and the other parts of the file are dropped, so dates stored
+// as numbers come back as CellTypeNumber.
+//
+// Date cells that can not be represented, e.g. dates before 1900, do not
+// stop the reading: they keep the text of their value as a string, and the
+// workbook is returned along with an error that lists them and wraps
+// ErrCellValue.
+func Read(r io.ReaderAt, size int64) (*Workbook, error) {
+	z, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, err
+	}
+	rd := &reader{files: map[string]*zip.File{}}
+	for _, f := range z.File {
+		rd.files["/"+strings.TrimPrefix(f.Name, "/")] = f
+	}
+	return rd.read()
+}
+
+// ReadFile is Read for a file on disk.
+func ReadFile(name string) (*Workbook, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	st, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return Read(f, st.Size())
+}
+
+// ErrCellValue is wrapped by the error that Read returns for cell values it
+// could not convert.
+var ErrCellValue = errors.New("invalid cell value")
+
+// Type returns the type of the value stored in the cell.
+func (c *Cell) Type() CellType {
+	return c.typ
+}
+
+// Value returns the value of the cell in its stored form: numbers and
+// dates as decimal numbers (dates as serial days), booleans as "1" or "0",
+// and errors as their code, e.g. "#N/A".
+func (c *Cell) Value() string {
+	return c.v
+}
+
+// Number returns the 1-based row number.
+func (r *Row) Number() int {
+	return r.rowNumber
+}
+
+type reader struct {
+	files         map[string]*zip.File // by absolute part name
+	sharedStrings []string
+	problems      []error // cell values that could not be converted
+}
+
+type xmlRels struct {
+	Rels []struct {
+		ID     string `xml:"Id,attr"`
+		Type   string `xml:"Type,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+type xmlWorkbook struct {
+	Properties struct {
+		Date1904 bool `xml:"date1904,attr"`
+	} `xml:"workbookPr"`
+	Sheets []struct {
+		Name  string `xml:"name,attr"`
+		State string `xml:"state,attr"`
+		RID   string `xml:"id,attr"` // r:id, the namespace differs in strict files
+	} `xml:"sheets>sheet"`
+}
+
+// xmlText is the content of a shared string or an inline string, either
+// plain or split into rich text runs.
+type xmlText struct {
+	T    string `xml:"t"`
+	Runs []struct {
+		T string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (t *xmlText) String() string {
+	if len(t.Runs) == 0 {
+		return decodeEscapes(t.T)
+	}
+	var sb strings.Builder
+	sb.WriteString(t.T)
+	for _, r := range t.Runs {
+		sb.WriteString(r.T)
+	}
+	return decodeEscapes(sb.String())
+}
+
+// decodeEscapes replaces the _xHHHH_ escape sequences of a string with the
+// characters they stand for, see Writer.EscapeControlChars.
+func decodeEscapes(s string) string {
+	if !strings.Contains(s, "_x") {
+		return s
+	}
+	var sb strings.Builder
+	for i := 0; i < len(s); {
+		if isEscapeSeq(s[i:]) {
+			r, _ := strconv.ParseUint(s[i+2:i+6], 16, 16)
+			sb.WriteRune(rune(r))
+			i += 7
+			continue
+		}
+		sb.WriteByte(s[i])
+		i++
+	}
+	return sb.String()
+}
+
+type xmlWorksheet struct {
+	Rows []struct {
+		R         int     `xml:"r,attr"`
+		Height    float32 `xml:"ht,attr"`
+		Hidden    bool    `xml:"hidden,attr"`
+		Level     int     `xml:"outlineLevel,attr"`
+		Collapsed bool    `xml:"collapsed,attr"`
+		Cells     []struct {
+			R  string   `xml:"r,attr"`
+			T  string   `xml:"t,attr"`
+			V  *string  `xml:"v"`
+			Is *xmlText `xml:"is"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+func (rd *reader) read() (*Workbook, error) {
+	rels, err := rd.rels("/")
+	if err != nil {
+		return nil, err
+	}
+	wbPath := ""
+	for _, rel := range rels.Rels {
+		if strings.HasSuffix(rel.Type, "/officeDocument") {
+			wbPath = resolvePart("/", rel.Target)
+		}
+	}
+	if wbPath == "" {
+		return nil, fmt.Errorf("not a spreadsheet: missing the workbook part")
+	}
+
+	var xwb xmlWorkbook
+	if err = rd.unmarshal(wbPath, &xwb); err != nil {
+		return nil, err
+	}
+	if rels, err = rd.rels(wbPath); err != nil {
+		return nil, err
+	}
+	targets := map[string]string{}
+	for _, rel := range rels.Rels {
+		target := resolvePart(wbPath, rel.Target)
+		targets[rel.ID] = target
+		if strings.HasSuffix(rel.Type, "/sharedStrings") {
+			if err = rd.readSharedStrings(target); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	wb := NewWorkbook()
+	wb.Date1904 = xwb.Properties.Date1904
+	for _, xsh := range xwb.Sheets {
+		sh, err := wb.AddSheet(xsh.Name)
+		if err != nil {
+			return nil, err
+		}
+		switch xsh.State {
+		case "hidden":
+			sh.Visibility = VisibilityHidden
+		case "veryHidden":
+			sh.Visibility = VisibilityVeryHidden
+		}
+		target, ok := targets[xsh.RID]
+		if !ok {
+			return nil, fmt.Errorf("sheet '%s': missing relationship %s", xsh.Name, xsh.RID)
+		}
+		if err = rd.readSheet(sh, target); err != nil {
+			return nil, fmt.Errorf("sheet '%s': %w", xsh.Name, err)
+		}
+	}
+	if len(rd.problems) > 0 {
+		return wb, errors.Join(rd.problems...)
+	}
+	return wb, nil
+}
+
+// rels reads the relationships of a part, "/" for the package.
+func (rd *reader) rels(part string) (*xmlRels, error) {
+	dir, name := path.Split(part)
+	relsPath := dir + "_rels/" + name + ".rels"
+	rels := &xmlRels{}
+	if _, ok := rd.files[relsPath]; !ok {
+		return rels, nil
+	}
+	return rels, rd.unmarshal(relsPath, rels)
+}
+
+// resolvePart returns the absolute name of a relationship target, which is
+// relative to the folder of the source part unless it starts with a slash.
+func resolvePart(source, target string) string {
+	if strings.HasPrefix(target, "/") {
+		return path.Clean(target)
+	}
+	return path.Join(path.Dir(source), target)
+}
+
+func (rd *reader) unmarshal(part string, v any) error {
+	f, ok := rd.files[part]
+	if !ok {
+		return fmt.Errorf("missing part %s", part)
+	}
+	r, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	if err = xml.NewDecoder(r).Decode(v); err != nil {
+		return fmt.Errorf("%s: %w", part, err)
+	}
+	return nil
+}
+
+func (rd *reader) readSharedStrings(part string) error {
+	var sst struct {
+		Items []xmlText `xml:"si"`
+	}
+	if err := rd.unmarshal(part, &sst); err != nil {
+		return err
+	}
+	rd.sharedStrings = make([]string, len(sst.Items))
+	for i := range sst.Items {
+		rd.sharedStrings[i] = sst.Items[i].String()
+	}
+	return nil
+}
+
+func (rd *reader) readSheet(sh *Sheet, part string) error {
+	var xsh xmlWorksheet
+	if err := rd.unmarshal(part, &xsh); err != nil {
+		return err
+	}
+	rowNumber := 0
+	for _, xr := range xsh.Rows {
+		// the row and cell references are optional, they default to the
+		// next row or column
+		rowNumber++
+		if xr.R != 0 {
+			rowNumber = xr.R
+		}
+		if rowNumber < 1 || rowNumber > MaxRowNumber {
+			return fmt.Errorf("invalid row number %d", rowNumber)
+		}
+		row := sh.rowAt(rowNumber)
+		row.Height = xr.Height
+		row.Hidden = xr.Hidden
+		row.OutlineLevel = xr.Level
+		row.Collapsed = xr.Collapsed
+
+		col := 0
+		for _, xc := range xr.Cells {
+			col++
+			if xc.R != "" {
+				c, r, err := parseCellRef(xc.R)
+				if err != nil {
+					return err
+				}
+				if r != rowNumber {
+					return fmt.Errorf("cell %s is outside of row %d", xc.R, rowNumber)
+				}
+				col = c
+			}
+			cell := row.AddCellAt(col)
+
+			v := ""
+			if xc.V != nil {
+				v = *xc.V
+			}
+			switch xc.T {
+			case "", "n":
+				if xc.V == nil {
+					continue
+				}
+				f, err := strconv.ParseFloat(v, 64)
+				if err != nil {
+					return fmt.Errorf("cell %s: invalid number '%s'", cell.coord, v)
+				}
+				cell.SetFloat(f)
+			case "s":
+				i, err := strconv.Atoi(v)
+				if err != nil || i < 0 || i >= len(rd.sharedStrings) {
+					return fmt.Errorf("cell %s: shared string index '%s' is out of range", cell.coord, v)
+				}
+				cell.SetStr(rd.sharedStrings[i])
+			case "str":
+				// the cached result of a formula
+				cell.SetStr(v)
+			case "inlineStr":
+				if xc.Is != nil {
+					cell.SetInlineStr(xc.Is.String())
+				}
+			case "b":
+				cell.SetBool(v == "1" || v == "true")
+			case "e":
+				cell.typ = CellTypeError
+				cell.v = v
+			case "d":
+				if err := readDate(cell, v); err != nil {
+					cell.SetStr(v)
+					rd.problems = append(rd.problems, fmt.Errorf("sheet '%s', cell %s: %w: %v", sh.Name, cell.coord, ErrCellValue, err))
+				}
+			default:
+				return fmt.Errorf("cell %s: unknown cell type '%s'", cell.coord, xc.T)
+			}
+		}
+	}
+	return nil
+}
+
+// readDate stores the value of a date cell, which is an ISO 8601 date, time
+// or combined date and time. A time without a date is stored as a number,
+// the fraction of the day, which is how Excel represents times.
+func readDate(c *Cell, s string) error {
+	for _, layout := range []string{
+		"2006-01-02T15:04:05.999999999Z07:00",
+		"2006-01-02T15:04:05.999999999",
+		"2006-01-02",
+	} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return c.SetDateTime(t)
+		}
+	}
+	for _, layout := range []string{
+		"15:04:05.999999999Z07:00",
+		"15:04:05.999999999",
+		"15:04",
+	} {
+		if t, err := time.Parse(layout, s); err == nil {
+			d := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute +
+				time.Duration(t.Second())*time.Second + time.Duration(t.Nanosecond())
+			c.SetFloat(d.Hours() / 24)
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid date '%s'", s)
+}
@@ -0,0 +1,98 @@
+package xl
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// readSheetData reads a minimal package with a single sheet "Data", whose
+// rows are given as the content of its sheetData.
+func readSheetData(t *testing.T, sheetData string) (*Workbook, error) {
+	t.Helper()
+	parts := map[string]string{
+		"_rels/.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+			`</Relationships>`,
+		"xl/workbook.xml": `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+			`<sheets><sheet name="Data" sheetId="1" r:id="rId1"/></sheets></workbook>`,
+		"xl/_rels/workbook.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+			`</Relationships>`,
+		"xl/worksheets/sheet1.xml": `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+			`<sheetData>` + sheetData + `</sheetData></worksheet>`,
+	}
+	var bb bytes.Buffer
+	z := zip.NewWriter(&bb)
+	for name, content := range parts {
+		f, err := z.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = f.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := z.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return Read(bytes.NewReader(bb.Bytes()), int64(bb.Len()))
+}
+
+func TestReadRows(t *testing.T) {
+	wb, err := readSheetData(t, `<row r="2" hidden="1" outlineLevel="1"><c r="A2"><v>1</v></c></row>`+
+		`<row r="3" collapsed="1"><c r="A3"><v>2</v></c></row>`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows := wb.Sheets[0].Rows
+	if len(rows) != 2 {
+		t.Fatalf("got %d rows, want 2", len(rows))
+	}
+	if r := rows[0]; r.Number() != 2 || !r.Hidden || r.OutlineLevel != 1 || r.Collapsed {
+		t.Errorf("row 2: got %d %v %d %v", r.Number(), r.Hidden, r.OutlineLevel, r.Collapsed)
+	}
+	if r := rows[1]; r.Number() != 3 || r.Hidden || r.OutlineLevel != 0 || !r.Collapsed {
+		t.Errorf("row 3: got %d %v %d %v", r.Number(), r.Hidden, r.OutlineLevel, r.Collapsed)
+	}
+}
+
+func TestReadDates(t *testing.T) {
+	wb, err := readSheetData(t, `<row r="1">`+
+		`<c r="A1" t="d"><v>2024-03-01</v></c>`+
+		`<c r="B1" t="d"><v>2024-03-01T12:00:00</v></c>`+
+		`<c r="C1" t="d"><v>18:00:00</v></c>`+
+		`<c r="D1" t="d"><v>1899-01-01</v></c>`+
+		`<c r="E1" t="d"><v>soon</v></c>`+
+		`<c r="F1"><v>7</v></c>`+
+		`</row>`)
+	if !errors.Is(err, ErrCellValue) {
+		t.Fatalf("got %v, want ErrCellValue", err)
+	}
+	for _, want := range []string{"sheet 'Data', cell D1: invalid cell value", "sheet 'Data', cell E1: invalid cell value"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %q", err, want)
+		}
+	}
+	if wb == nil {
+		t.Fatal("no workbook returned along with the error")
+	}
+	cells := wb.Sheets[0].Rows[0].Cells
+	for i, want := range []struct {
+		typ CellType
+		v   string
+	}{
+		{CellTypeDate, "45352"},
+		{CellTypeDate, "45352.5"},
+		{CellTypeNumber, "0.75"},
+		{CellTypeSharedString, "1899-01-01"},
+		{CellTypeSharedString, "soon"},
+		{CellTypeNumber, "7"},
+	} {
+		if c := cells[i]; c.Type() != want.typ || c.Value() != want.v {
+			t.Errorf("cell %s: got %v %q, want %v %q", c.coord, c.Type(), c.Value(), want.typ, want.v)
+		}
+	}
+}
@@ -1,12 +1,25 @@
 package xl
 
-import "strconv"
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
 
 type Row struct {
 	Cells []*Cell
 
 	Height float32 // when Height=0, use default ~30?
 
+	// OutlineLevel groups the row into collapsible outlines, from 0 (not
+	// grouped) to 7, see Sheet.GroupRows. Hidden hides the row, which is
+	// how the rows of a collapsed group are stored, and Collapsed marks the
+	// summary row of a collapsed group.
+	OutlineLevel int
+	Hidden       bool
+	Collapsed    bool
+
 	sheet            *Sheet
 	rowNumber        int // 1-based
 	nextColumnNumber int // 1-based, incremented as we add cells
@@ -23,6 +36,63 @@ func (r *Row) AddCell() *Cell {
 	return c
 }
 
+// Cell returns the cell at the given 1-based column number, creating it if
+// necessary. The columns before it that have no cell are filled with unset
+// cells, so that afterwards Cells[col-1] is the cell of column col; use
+// AddCellAt to leave them out. Subsequent AddCell calls continue after the
+// rightmost cell.
+func (r *Row) Cell(col int) (*Cell, error) {
+	if col < 1 || col > MaxColumnNumber {
+		return nil, fmt.Errorf("invalid column number %d", col)
+	}
+	if len(r.Cells) >= col && r.Cells[col-1].columnNumber == col {
+		// cells are ordered and unique, so the columns before are filled
+		return r.Cells[col-1], nil
+	}
+	cells := make([]*Cell, 0, max(len(r.Cells)+1, col))
+	j := 0
+	for n := 1; n <= col; n++ {
+		if j < len(r.Cells) && r.Cells[j].columnNumber == n {
+			cells = append(cells, r.Cells[j])
+			j++
+			continue
+		}
+		cells = append(cells, &Cell{
+			row:          r,
+			columnNumber: n,
+			coord:        CellCoordAsString(n, r.rowNumber),
+		})
+	}
+	r.Cells = append(cells, r.Cells[j:]...)
+	r.nextColumnNumber = max(r.nextColumnNumber, col+1)
+	return r.Cells[col-1], nil
+}
+
+// AddCellAt adds a cell at the given 1-based column number, leaving the
+// columns before it empty, so that rows can have holes. Cells are kept
+// ordered by column; if the column already has a cell, that cell is
+// returned. Subsequent AddCell calls continue after the rightmost cell.
+// It panics if col is less than 1.
+func (r *Row) AddCellAt(col int) *Cell {
+	if col < 1 {
+		panic("invalid column number")
+	}
+	i, found := slices.BinarySearchFunc(r.Cells, col, func(c *Cell, col int) int {
+		return c.columnNumber - col
+	})
+	if found {
+		return r.Cells[i]
+	}
+	c := &Cell{
+		row:          r,
+		columnNumber: col,
+		coord:        CellCoordAsString(col, r.rowNumber),
+	}
+	r.Cells = slices.Insert(r.Cells, i, c)
+	r.nextColumnNumber = max(r.nextColumnNumber, col+1)
+	return c
+}
+
 func ColumnNumberAsLetters(n int) string {
 	if n < 1 {
 		panic("invalid column number")
@@ -41,3 +111,29 @@ func CellCoordAsString(col, row int) string {
 	}
 	return ColumnNumberAsLetters(col) + strconv.Itoa(row)
 }
+
+// parseCellRef parses an A1-style cell reference, absolute markers ($A$1)
+// are accepted and ignored.
+func parseCellRef(ref string) (col, row int, err error) {
+	s := strings.TrimPrefix(strings.ToUpper(ref), "$")
+	i := 0
+	for i < len(s) && s[i] >= 'A' && s[i] <= 'Z' {
+		col = col*26 + int(s[i]-'A'+1)
+		if col > MaxColumnNumber {
+			return 0, 0, fmt.Errorf("invalid cell reference '%s'", ref)
+		}
+		i++
+	}
+	if i == 0 {
+		return 0, 0, fmt.Errorf("invalid cell reference '%s'", ref)
+	}
+	digits := strings.TrimPrefix(s[i:], "$")
+	if digits == "" || digits[0] == '0' || digits[0] == '+' || digits[0] == '-' {
+		return 0, 0, fmt.Errorf("invalid cell reference '%s'", ref)
+	}
+	row, err = strconv.Atoi(digits)
+	if err != nil || row > MaxRowNumber {
+		return 0, 0, fmt.Errorf("invalid cell reference '%s'", ref)
+	}
+	return col, row, nil
+}
@@ -0,0 +1,52 @@
+package xl
+
+import "testing"
+
+func TestRowCell(t *testing.T) {
+	wb, sh := newTestSheet(t, "Data")
+	r := sh.AddRow()
+	r.AddCell().SetStr("a")
+	r.AddCellAt(4).SetStr("d")
+
+	c, err := r.Cell(6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.SetStr("f")
+	if len(r.Cells) != 6 {
+		t.Fatalf("got %d cells, want 6", len(r.Cells))
+	}
+	for i, c := range r.Cells {
+		if c.columnNumber != i+1 || c.coord != CellCoordAsString(i+1, 1) {
+			t.Errorf("cell %d: column %d, %s", i, c.columnNumber, c.coord)
+		}
+	}
+	for _, col := range []int{1, 4, 6} {
+		c, err := r.Cell(col)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c != r.Cells[col-1] || c.Type() != CellTypeSharedString {
+			t.Errorf("column %d: got %v %q", col, c.Type(), c.Value())
+		}
+	}
+	if r.Cells[1].Type() != CellTypeUnset || r.Cells[4].Type() != CellTypeUnset {
+		t.Error("filled cells are not unset")
+	}
+	if c := r.AddCell(); c.columnNumber != 7 {
+		t.Errorf("AddCell after Cell: got column %d, want 7", c.columnNumber)
+	}
+	for _, col := range []int{0, -1, MaxColumnNumber + 1} {
+		if _, err := r.Cell(col); err == nil {
+			t.Errorf("Cell(%d) accepted", col)
+		}
+	}
+
+	// the filled cells are not written
+	wantContains(t, writeParts(t, wb).part(t, "/xl/worksheets/sheet1.xml"),
+		`<row r="1">`+"\n"+
+			`      <c r="A1" t="s"><v>0</v></c>`+"\n"+
+			`      <c r="D1" t="s"><v>1</v></c>`+"\n"+
+			`      <c r="F1" t="s"><v>2</v></c>`+"\n"+
+			`    </row>`)
+}
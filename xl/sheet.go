@@ -1,16 +1,153 @@
 package xl
 
+import (
+	"errors"
+	"fmt"
+	"slices"
+)
+
 type Sheet struct {
 	Name    string
 	Rows    []*Row
 	Columns map[int]*Column // 1-based
 
-	workbook      *Workbook
-	nextRowNumber int // 1-based, incremented as we add rows
+	// CodeName is the VBA code name of the sheet.
+	CodeName string
+
+	// EnableFormatConditionsCalculation controls whether Excel evaluates
+	// conditional formats on the sheet; nil leaves the default (enabled).
+	// Disabling it speeds up sheets with many conditional formats.
+	EnableFormatConditionsCalculation *bool
+
+	// RawData selects the "raw data" mode for high-throughput exports: all
+	// cell, column and default styles of the sheet are ignored and no style
+	// resolution takes place. When every sheet is in this mode the styles
+	// part is not generated at all.
+	RawData bool
+
+	// DefaultStyle applies to all cells of the sheet that do not have a
+	// style of their own or a column style. A cell style, when set,
+	// replaces it entirely, except for the number format which is
+	// inherited when the cell style has none.
+	DefaultStyle XF
+
+	// DefaultRowHeight (in points) and DefaultColWidth (in characters)
+	// size the rows and columns that have no size of their own; zero keeps
+	// the Excel defaults.
+	DefaultRowHeight float32
+	DefaultColWidth  float32
+
+	// SummaryRowsAbove places the summary rows of row groups above the
+	// detail rows rather than below, which moves the expand/collapse
+	// buttons accordingly.
+	SummaryRowsAbove bool
+
+	// SummaryColumnsLeft places the summary columns of column groups to
+	// the left of the detail columns rather than to the right.
+	SummaryColumnsLeft bool
+
+	// PageSetup controls printing, see also SetPrintArea.
+	PageSetup PageSetup
+
+	// HeaderFooter sets the texts printed at the top and bottom of pages.
+	HeaderFooter HeaderFooter
+
+	// TabColor is the color of the sheet tab, RGB as "00B050" or
+	// "#00B050"; empty for the default.
+	TabColor string
+
+	// Visibility hides the sheet tab, at least one sheet of the workbook
+	// must stay visible.
+	Visibility Visibility
+
+	// ZoomScale is the magnification of the sheet window in percent, from
+	// 10 to 400; zero selects the default (100).
+	ZoomScale int
+
+	// ViewType selects the normal, page layout or page break preview view,
+	// see the View constants; empty for normal.
+	ViewType string
+
+	// ShowGridlines controls the display of gridlines, nil leaves the
+	// default (shown).
+	ShowGridlines *bool
+
+	// ShowRowColHeaders controls the display of the row numbers and column
+	// letters, nil leaves the default (shown).
+	ShowRowColHeaders *bool
+
+	// RightToLeft lays the sheet out from right to left, with column A on
+	// the right, for right-to-left languages.
+	RightToLeft bool
+
+	workbook        *Workbook
+	nextRowNumber   int // 1-based, incremented as we add rows
+	merges          []cellRange
+	mergeIndex      mergeIndex
+	topLeftCell     string
+	activeCell      string
+	pane            *pane
+	printArea       *cellRange
+	printTitleRows  [2]int // first and last, zero when not set
+	printTitleCols  [2]int
+	condFormats     []*conditionalFormat
+	protection      *SheetProtection
+	dataValidations []*dataValidation
+}
+
+// Visibility is the visibility state of a sheet.
+type Visibility int
+
+const (
+	VisibilityVisible    Visibility = iota
+	VisibilityHidden                // can be unhidden from the Excel UI
+	VisibilityVeryHidden            // can only be unhidden with VBA
+)
+
+func (v Visibility) state() string {
+	switch v {
+	case VisibilityHidden:
+		return "hidden"
+	case VisibilityVeryHidden:
+		return "veryHidden"
+	}
+	return ""
+}
+
+// checkSheetVisibility verifies the visibility states of the sheets, Excel
+// refuses to open a workbook without a visible sheet.
+func checkSheetVisibility(wb *Workbook) error {
+	visible := false
+	for _, sh := range wb.Sheets {
+		if sh.Visibility < VisibilityVisible || sh.Visibility > VisibilityVeryHidden {
+			return fmt.Errorf("sheet '%s': invalid visibility %d", sh.Name, sh.Visibility)
+		}
+		if sh.Visibility == VisibilityVisible {
+			visible = true
+		}
+	}
+	if !visible && len(wb.Sheets) > 0 {
+		return errors.New("at least one sheet must be visible")
+	}
+	return nil
 }
 
 type Column struct {
 	Width float32
+
+	// Hidden hides the column, OutlineLevel groups it like
+	// Row.OutlineLevel, see Sheet.GroupColumns.
+	Hidden       bool
+	OutlineLevel int
+
+	// Style applies to the cells of the column that do not have a style of
+	// their own, it takes precedence over the sheet DefaultStyle. Its
+	// number format is also inherited by styled cells that have none.
+	Style XF
+}
+
+func (c *Column) empty() bool {
+	return c.Width <= 0 && c.Style.Empty() && !c.Hidden && c.OutlineLevel == 0
 }
 
 func (s *Sheet) AddRow() *Row {
@@ -24,21 +161,214 @@ func (s *Sheet) AddRow() *Row {
 	return r
 }
 
+// SetNextRow sets the number of the row created by the next AddRow call,
+// leaving the rows in between empty, e.g. for a banner area above the
+// data. Rows are kept in order, so n can not go back to an existing row.
+func (s *Sheet) SetNextRow(n int) error {
+	if n < s.nextRowNumber {
+		return fmt.Errorf("row %d is before the next available row %d", n, s.nextRowNumber)
+	}
+	if n > MaxRowNumber {
+		return fmt.Errorf("row %d exceeds the limit of %d rows", n, MaxRowNumber)
+	}
+	s.nextRowNumber = n
+	return nil
+}
+
+// AddRowAt adds a row with the given 1-based row number, see SetNextRow.
+func (s *Sheet) AddRowAt(n int) (*Row, error) {
+	if err := s.SetNextRow(n); err != nil {
+		return nil, err
+	}
+	return s.AddRow(), nil
+}
+
+// Cell returns the cell at an A1-style reference, creating it and its row
+// if necessary, so that cells can be set in any order. Rows that are never
+// referenced are left out of the sheet.
+func (s *Sheet) Cell(ref string) (*Cell, error) {
+	col, row, err := parseCellRef(ref)
+	if err != nil {
+		return nil, err
+	}
+	return s.rowAt(row).AddCellAt(col), nil
+}
+
+// rowAt returns the row with the given 1-based number, inserting it in
+// order if it does not exist.
+func (s *Sheet) rowAt(n int) *Row {
+	i, found := slices.BinarySearchFunc(s.Rows, n, func(r *Row, n int) int {
+		return r.rowNumber - n
+	})
+	if found {
+		return s.Rows[i]
+	}
+	r := &Row{
+		sheet:            s,
+		rowNumber:        n,
+		nextColumnNumber: 1,
+	}
+	s.Rows = slices.Insert(s.Rows, i, r)
+	s.nextRowNumber = max(s.nextRowNumber, n+1)
+	return r
+}
+
 func (s *Sheet) SetColumnWidth(colNumber int, w float32) {
 	if colNumber <= 0 {
 		return
 	}
-	if w <= 0.0 {
+	s.updateColumn(colNumber, func(c *Column) {
+		c.Width = max(w, 0)
+	})
+}
+
+// SetColumnStyle sets the default style of a column, see Column.Style.
+func (s *Sheet) SetColumnStyle(colNumber int, xf XF) {
+	if colNumber <= 0 {
+		return
+	}
+	s.updateColumn(colNumber, func(c *Column) {
+		c.Style = xf
+	})
+}
+
+func (s *Sheet) updateColumn(colNumber int, update func(c *Column)) {
+	c, exists := s.Columns[colNumber]
+	if !exists {
+		c = &Column{}
+	}
+	update(c)
+	if c.empty() {
 		delete(s.Columns, colNumber)
 	} else {
-		c, exists := s.Columns[colNumber]
-		if !exists {
-			c = &Column{
-				Width: w,
-			}
-		} else {
-			c.Width = w
-		}
 		s.Columns[colNumber] = c
 	}
 }
+
+// SetTopLeftCell sets the cell shown in the top-left corner of the window
+// when the sheet is opened, i.e. where the view is scrolled to. An empty
+// ref resets it to the default (A1).
+func (s *Sheet) SetTopLeftCell(ref string) error {
+	if ref == "" {
+		s.topLeftCell = ""
+		return nil
+	}
+	col, row, err := parseCellRef(ref)
+	if err != nil {
+		return err
+	}
+	s.topLeftCell = CellCoordAsString(col, row)
+	return nil
+}
+
+// GroupRows sets the outline level of rows startRow to endRow (1-based,
+// inclusive), creating the rows if necessary. Nested groups have higher
+// levels, up to MaxOutlineLevel; level 0 ungroups the rows.
+func (s *Sheet) GroupRows(startRow, endRow, level int) error {
+	if level < 0 || level > MaxOutlineLevel {
+		return fmt.Errorf("outline level %d is out of the range 0-%d", level, MaxOutlineLevel)
+	}
+	if startRow < 1 || endRow < startRow || endRow > MaxRowNumber {
+		return fmt.Errorf("invalid row range %d-%d", startRow, endRow)
+	}
+	for n := startRow; n <= endRow; n++ {
+		s.rowAt(n).OutlineLevel = level
+	}
+	return nil
+}
+
+// GroupColumns sets the outline level of columns startCol to endCol
+// (1-based, inclusive), see GroupRows.
+func (s *Sheet) GroupColumns(startCol, endCol, level int) error {
+	if level < 0 || level > MaxOutlineLevel {
+		return fmt.Errorf("outline level %d is out of the range 0-%d", level, MaxOutlineLevel)
+	}
+	if startCol < 1 || endCol < startCol || endCol > MaxColumnNumber {
+		return fmt.Errorf("invalid column range %d-%d", startCol, endCol)
+	}
+	for n := startCol; n <= endCol; n++ {
+		s.updateColumn(n, func(c *Column) {
+			c.OutlineLevel = level
+		})
+	}
+	return nil
+}
+
+// SetColumnHidden hides or shows a column.
+func (s *Sheet) SetColumnHidden(colNumber int, hidden bool) {
+	if colNumber <= 0 {
+		return
+	}
+	s.updateColumn(colNumber, func(c *Column) {
+		c.Hidden = hidden
+	})
+}
+
+// maxColOutlineLevel returns the highest outline level of the columns.
+func (s *Sheet) maxColOutlineLevel() int {
+	level := 0
+	for _, c := range s.Columns {
+		level = max(level, c.OutlineLevel)
+	}
+	return level
+}
+
+// maxRowOutlineLevel returns the highest outline level of the rows.
+func (s *Sheet) maxRowOutlineLevel() int {
+	level := 0
+	for _, r := range s.Rows {
+		level = max(level, r.OutlineLevel)
+	}
+	return level
+}
+
+// RowCount returns the number of rows added to the sheet.
+func (s *Sheet) RowCount() int {
+	return len(s.Rows)
+}
+
+// MaxColumn returns the largest column number used by any row, which is
+// the width of the sheet.
+func (s *Sheet) MaxColumn() int {
+	maxCol, _ := s.dataExtent()
+	return maxCol
+}
+
+// dataExtent returns the largest column and row numbers that have cells.
+func (s *Sheet) dataExtent() (maxCol, maxRow int) {
+	for _, r := range s.Rows {
+		if len(r.Cells) == 0 {
+			continue
+		}
+		maxRow = max(maxRow, r.rowNumber)
+		for _, c := range r.Cells {
+			maxCol = max(maxCol, c.columnNumber)
+		}
+	}
+	return
+}
+
+// AddTitle adds a title banner as the first row of the sheet: text is
+// written to A1, which is merged across widthCols columns and styled with
+// style, centered horizontally. The data rows added afterwards start at
+// row 2, call SetNextRow to leave a gap below the title. The title must be
+// added before any other row.
+func (s *Sheet) AddTitle(text string, widthCols int, style XF) (*Cell, error) {
+	if s.nextRowNumber != 1 {
+		return nil, fmt.Errorf("sheet '%s': title must be added before other rows", s.Name)
+	}
+	if widthCols < 1 || widthCols > MaxColumnNumber {
+		return nil, fmt.Errorf("invalid title width: %d columns", widthCols)
+	}
+	if widthCols > 1 {
+		err := s.MergeCells(cellRange{minCol: 1, minRow: 1, maxCol: widthCols, maxRow: 1}.String())
+		if err != nil {
+			return nil, err
+		}
+	}
+	c := s.AddRow().AddCell()
+	c.SetStr(text)
+	c.XF = style
+	c.Alignment.Horizontal = "center"
+	return c, nil
+}
@@ -0,0 +1,144 @@
+package xl
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// structField describes a struct field exported to a cell, as configured
+// with the `xl:"Header,format=0.00"` tag. Fields tagged `xl:"-"` are
+// skipped, the header defaults to the field name.
+type structField struct {
+	index  int
+	header string
+	format string
+}
+
+func structFields(t reflect.Type) []structField {
+	var ff []structField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		tag := f.Tag.Get("xl")
+		if tag == "-" {
+			continue
+		}
+		sf := structField{index: i, header: f.Name}
+		name, opts, _ := strings.Cut(tag, ",")
+		if name != "" {
+			sf.header = name
+		}
+		for _, opt := range strings.Split(opts, ",") {
+			if k, v, ok := strings.Cut(opt, "="); ok && k == "format" {
+				sf.format = v
+			}
+		}
+		ff = append(ff, sf)
+	}
+	return ff
+}
+
+func structValue(v any) (reflect.Value, error) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return rv, errors.New("nil struct pointer")
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return rv, fmt.Errorf("expected a struct, got %s", rv.Type())
+	}
+	return rv, nil
+}
+
+// AppendStruct adds a row with a cell for every exported field of v, which
+// must be a struct or a pointer to a struct. Number formats specified in
+// the field tags are applied to the cells.
+func (s *Sheet) AppendStruct(v any) (*Row, error) {
+	rv, err := structValue(v)
+	if err != nil {
+		return nil, err
+	}
+	r := s.AddRow()
+	for _, f := range structFields(rv.Type()) {
+		c := r.AddCell()
+		c.NumFmt = f.format
+		err = setReflectValue(c, rv.Field(f.index))
+		if err != nil {
+			return r, fmt.Errorf("field %s: %w", rv.Type().Field(f.index).Name, err)
+		}
+	}
+	return r, nil
+}
+
+// WriteStructs writes a header row built from the field tags, styled with
+// headerStyle, followed by a row for every element of slice, which must be
+// a slice of structs or of pointers to structs.
+func (s *Sheet) WriteStructs(slice any, headerStyle XF) error {
+	rv := reflect.ValueOf(slice)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return fmt.Errorf("expected a slice, got %T", slice)
+	}
+	t := rv.Type().Elem()
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return fmt.Errorf("expected a slice of structs, got %T", slice)
+	}
+
+	r := s.AddRow()
+	for _, f := range structFields(t) {
+		c := r.AddCell()
+		c.SetStr(f.header)
+		c.XF = headerStyle
+	}
+
+	for i := 0; i < rv.Len(); i++ {
+		_, err := s.AppendStruct(rv.Index(i).Interface())
+		if err != nil {
+			return fmt.Errorf("element %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// setReflectValue sets the cell value from a field of a supported kind,
+// nil pointers leave the cell unset.
+func setReflectValue(c *Cell, v reflect.Value) error {
+	if !v.IsValid() {
+		c.Clear()
+		return nil
+	}
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			c.Clear()
+			return nil
+		}
+		v = v.Elem()
+	}
+	if t, ok := v.Interface().(time.Time); ok {
+		return c.SetDateTime(t)
+	}
+	switch v.Kind() {
+	case reflect.Bool:
+		c.SetBool(v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		c.SetInt(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		c.setUint(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		c.SetFloat(v.Float())
+	case reflect.String:
+		c.SetStr(v.String())
+	default:
+		return fmt.Errorf("unsupported value type %s", v.Type())
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package xl
+
+import (
+	"math"
+	"testing"
+)
+
+type record struct {
+	Name   string  `xl:"Full Name"`
+	Amount float64 `xl:",format=0.00"`
+	Count  uint64
+	Note   *string
+	secret int
+	Skip   int `xl:"-"`
+}
+
+func TestWriteStructs(t *testing.T) {
+	wb, sh := newTestSheet(t, "Sheet1")
+	note := "n"
+	err := sh.WriteStructs([]*record{
+		{Name: "a", Amount: 1.5, Count: math.MaxUint64, Note: &note},
+		{Name: "b", Amount: -2, Count: 1 << 53},
+	}, XF{Font: Font{Bold: true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		ref, want string
+	}{
+		{"A1", "Full Name"}, {"B1", "Amount"}, {"C1", "Count"}, {"D1", "Note"},
+		{"A2", "a"}, {"B2", "1.5"}, {"C2", "18446744073709551615"}, {"D2", "n"},
+		{"B3", "-2"}, {"C3", "9007199254740992"}, {"D3", ""},
+	} {
+		if v := mustCell(t, sh, tc.ref).Value(); v != tc.want {
+			t.Errorf("%s = %q, want %q", tc.ref, v, tc.want)
+		}
+	}
+	if n := len(sh.Rows[0].Cells); n != 4 {
+		t.Errorf("got %d columns, want 4", n)
+	}
+	wantContains(t, writeParts(t, wb).part(t, "/xl/worksheets/sheet1.xml"),
+		`<c r="C2" t="n"><v>18446744073709551615</v></c>`)
+
+	if err := sh.WriteStructs([]int{1}, XF{}); err == nil {
+		t.Error("accepted a slice of ints")
+	}
+}
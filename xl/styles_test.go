@@ -0,0 +1,90 @@
+package xl
+
+import (
+	"strings"
+	"testing"
+)
+
+// writeStyleErr writes a workbook with a single cell of the given format
+// and returns the error.
+func writeStyleErr(t *testing.T, xf XF) error {
+	t.Helper()
+	wb, sh := newTestSheet(t, "Sheet1")
+	c := mustCell(t, sh, "A1")
+	c.SetStr("x")
+	c.XF = xf
+	return NewWriter(memStorage{}).Write(wb)
+}
+
+func TestFillPattern(t *testing.T) {
+	wb, sh := newTestSheet(t, "Sheet1")
+	mustCell(t, sh, "A1").XF = XF{Fill: Fill{FgColor: "FFFF00"}}
+	mustCell(t, sh, "A2").XF = XF{Fill: Fill{PatternType: "lightGrid", FgColor: "FF0000", BgColor: "00FF00"}}
+	wantContains(t, writeParts(t, wb).part(t, "/xl/styles.xml"),
+		`<fill><patternFill patternType="solid"><fgColor rgb="FFFFFF00"/></patternFill></fill>`,
+		`<fill><patternFill patternType="lightGrid"><fgColor rgb="FFFF0000"/><bgColor rgb="FF00FF00"/></patternFill></fill>`)
+
+	err := writeStyleErr(t, XF{Fill: Fill{PatternType: "polkaDots"}})
+	if err == nil || !strings.Contains(err.Error(), "invalid fill pattern 'polkaDots'") {
+		t.Errorf("got %v, want an invalid fill pattern error", err)
+	}
+}
+
+func TestBorderStyle(t *testing.T) {
+	wb, sh := newTestSheet(t, "Sheet1")
+	mustCell(t, sh, "A1").XF = XF{Border: Border{
+		Left:   BorderEdge{Style: "thin"},
+		Bottom: BorderEdge{Style: "double", Color: "#FF0000"},
+	}}
+	wantContains(t, writeParts(t, wb).part(t, "/xl/styles.xml"),
+		`<left style="thin"/><bottom style="double"><color rgb="FFFF0000"/></bottom>`)
+
+	err := writeStyleErr(t, XF{Border: Border{Top: BorderEdge{Style: "wavy"}}})
+	if err == nil || !strings.Contains(err.Error(), "invalid border style 'wavy'") {
+		t.Errorf("got %v, want an invalid border style error", err)
+	}
+}
+
+// TestStylesPart checks that the formats collected while the worksheets
+// are written make it into the styles part, which is decided afterwards.
+func TestStylesPart(t *testing.T) {
+	wb, sh := newTestSheet(t, "Sheet1")
+	mustCell(t, sh, "A1").SetStr("plain")
+	if _, ok := writeParts(t, wb)["/xl/styles.xml"]; ok {
+		t.Error("styles.xml written without styles")
+	}
+
+	c := mustCell(t, sh, "B2")
+	c.SetStr("bold")
+	c.XF = XF{Font: Font{Bold: true}}
+	m := writeParts(t, wb)
+	wantContains(t, m.part(t, "/xl/styles.xml"), `<cellXfs count="2">`, `<font><b/>`)
+	wantContains(t, m.part(t, "/xl/worksheets/sheet1.xml"), `<c r="B2" s="1" t="s">`)
+	wantContains(t, m.part(t, "/xl/_rels/workbook.xml.rels"),
+		`Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"`)
+	wantContains(t, m.part(t, "[Content_Types].xml"),
+		`PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"`)
+}
+
+func TestFontVertAlign(t *testing.T) {
+	wb, sh := newTestSheet(t, "Sheet1")
+	for ref, va := range map[string]string{"A1": "", "A2": VertAlignBaseline, "A3": VertAlignSuperscript, "A4": VertAlignSubscript} {
+		c := mustCell(t, sh, ref)
+		c.SetStr(ref)
+		c.XF = XF{Font: Font{Bold: true, VertAlign: va}}
+	}
+	m := writeParts(t, wb)
+	wantContains(t, m.part(t, "/xl/styles.xml"), `<fonts count="4">`, `<cellXfs count="4">`,
+		`<vertAlign val="superscript"/>`, `<vertAlign val="subscript"/>`)
+	wantContains(t, m.part(t, "/xl/worksheets/sheet1.xml"),
+		`<c r="A1" s="1" t="s">`, `<c r="A2" s="1" t="s">`, `<c r="A3" s="2" t="s">`, `<c r="A4" s="3" t="s">`)
+	if s := m.part(t, "/xl/styles.xml"); strings.Contains(s, "baseline") {
+		t.Error("baseline written to the styles part")
+	}
+
+	w := NewWriter(memStorage{})
+	i := w.styleIndex(&XF{Font: Font{Italic: true}})
+	if j := w.FindXF(&XF{Font: Font{Italic: true, VertAlign: VertAlignBaseline}}); j != i {
+		t.Errorf("FindXF: got %d, want %d", j, i)
+	}
+}
@@ -0,0 +1,94 @@
+package xl
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ThemeColors is the color scheme of the workbook theme. Colors are
+// specified as hex RGB values, either "4472C4" or "#4472C4". Cells, fonts
+// and fills that refer to theme colors by index resolve to these values.
+type ThemeColors struct {
+	Dark1             string
+	Light1            string
+	Dark2             string
+	Light2            string
+	Accent1           string
+	Accent2           string
+	Accent3           string
+	Accent4           string
+	Accent5           string
+	Accent6           string
+	Hyperlink         string
+	FollowedHyperlink string
+}
+
+// DefaultThemeColors is the standard Office color scheme.
+var DefaultThemeColors = ThemeColors{
+	Dark1:             "000000",
+	Light1:            "FFFFFF",
+	Dark2:             "44546A",
+	Light2:            "E7E6E6",
+	Accent1:           "4472C4",
+	Accent2:           "ED7D31",
+	Accent3:           "A5A5A5",
+	Accent4:           "FFC000",
+	Accent5:           "5B9BD5",
+	Accent6:           "70AD47",
+	Hyperlink:         "0563C1",
+	FollowedHyperlink: "954F72",
+}
+
+// SetTheme customizes the color scheme of the workbook theme. Colors that
+// are left empty are taken from DefaultThemeColors.
+func (wb *Workbook) SetTheme(colors ThemeColors) error {
+	def := DefaultThemeColors.slots()
+	slots := colors.slots()
+	for i, p := range slots {
+		if *p == "" {
+			*p = *def[i]
+			continue
+		}
+		v, err := normalizeRGB(*p)
+		if err != nil {
+			return fmt.Errorf("theme color %s: %w", themeColorNames[i], err)
+		}
+		*p = v
+	}
+	wb.themeColors = colors
+	return nil
+}
+
+var themeColorNames = []string{
+	"dk1", "lt1", "dk2", "lt2",
+	"accent1", "accent2", "accent3", "accent4", "accent5", "accent6",
+	"hlink", "folHlink"}
+
+func (tc *ThemeColors) slots() []*string {
+	return []*string{
+		&tc.Dark1, &tc.Light1, &tc.Dark2, &tc.Light2,
+		&tc.Accent1, &tc.Accent2, &tc.Accent3, &tc.Accent4, &tc.Accent5, &tc.Accent6,
+		&tc.Hyperlink, &tc.FollowedHyperlink}
+}
+
+// normalizeRGB converts "#rrggbb" or "rrggbb" to the uppercase "RRGGBB" form
+func normalizeRGB(s string) (string, error) {
+	v := strings.ToUpper(strings.TrimPrefix(s, "#"))
+	if len(v) != 6 || strings.Trim(v, "0123456789ABCDEF") != "" {
+		return "", fmt.Errorf("invalid RGB color '%s'", s)
+	}
+	return v, nil
+}
+
+// normalizeARGB returns an uppercase AARRGGBB color as used in styles, RGB
+// input gets an opaque alpha.
+func normalizeARGB(s string) (string, error) {
+	v := strings.ToUpper(strings.TrimPrefix(s, "#"))
+	if len(v) == 6 {
+		v = "FF" + v
+	}
+	if len(v) != 8 || strings.Trim(v, "0123456789ABCDEF") != "" {
+		return "", fmt.Errorf("invalid color '%s'", s)
+	}
+	return v, nil
+}
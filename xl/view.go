@@ -0,0 +1,95 @@
+package xl
+
+import "fmt"
+
+// Sheet view types.
+const (
+	ViewNormal           = "normal"
+	ViewPageLayout       = "pageLayout"
+	ViewPageBreakPreview = "pageBreakPreview"
+)
+
+// ViewOptions describe how the window of a sheet is set up when the file is
+// opened, see Sheet.SetupView. Zero values select the defaults.
+type ViewOptions struct {
+	FreezeCols int // number of leftmost columns kept visible
+	FreezeRows int // number of topmost rows kept visible
+
+	TopLeftCell string // cell scrolled to the top-left corner of the window
+	ActiveCell  string // selected cell, must be outside the frozen panes
+
+	ZoomScale         int    // see Sheet.ZoomScale
+	ViewType          string // see Sheet.ViewType
+	ShowGridlines     *bool  // see Sheet.ShowGridlines
+	ShowRowColHeaders *bool  // see Sheet.ShowRowColHeaders
+}
+
+// SetupView configures the frozen panes, scroll position, selection, zoom,
+// view type, gridlines and headings of the sheet at once, replacing the
+// previous settings. The options are validated together, so that e.g. the
+// selection can not end up in a frozen pane; on error the sheet is left
+// unchanged.
+func (s *Sheet) SetupView(opts ViewOptions) error {
+	p, err := newFrozenPane(opts.FreezeCols, opts.FreezeRows)
+	if err != nil {
+		return err
+	}
+
+	var topLeft, active string
+	if opts.TopLeftCell != "" {
+		col, row, err := parseCellRef(opts.TopLeftCell)
+		if err != nil {
+			return err
+		}
+		topLeft = CellCoordAsString(col, row)
+	}
+	if opts.ActiveCell != "" {
+		col, row, err := parseCellRef(opts.ActiveCell)
+		if err != nil {
+			return err
+		}
+		if p != nil && (col <= opts.FreezeCols || row <= opts.FreezeRows) {
+			return fmt.Errorf("active cell %s is in a frozen pane", opts.ActiveCell)
+		}
+		active = CellCoordAsString(col, row)
+	}
+	if err = checkZoomScale(opts.ZoomScale); err != nil {
+		return err
+	}
+	if err = checkViewType(opts.ViewType); err != nil {
+		return err
+	}
+
+	s.pane = p
+	s.topLeftCell = topLeft
+	s.activeCell = active
+	s.ZoomScale = opts.ZoomScale
+	s.ViewType = opts.ViewType
+	s.ShowGridlines = opts.ShowGridlines
+	s.ShowRowColHeaders = opts.ShowRowColHeaders
+	return nil
+}
+
+func checkZoomScale(z int) error {
+	if z != 0 && (z < MinZoomScale || z > MaxZoomScale) {
+		return fmt.Errorf("zoom scale %d%% is out of the range %d-%d%%", z, MinZoomScale, MaxZoomScale)
+	}
+	return nil
+}
+
+func checkViewType(v string) error {
+	switch v {
+	case "", ViewNormal, ViewPageLayout, ViewPageBreakPreview:
+		return nil
+	}
+	return fmt.Errorf("invalid view type '%s'", v)
+}
+
+// selectionPanes returns the panes that get a selection element, the
+// active one is last.
+func (p *pane) selectionPanes() []string {
+	if p.xSplit > 0 && p.ySplit > 0 {
+		return []string{"topRight", "bottomLeft", "bottomRight"}
+	}
+	return []string{p.activePane()}
+}
@@ -0,0 +1,77 @@
+package xl
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestSetupView checks the schema order of the sheetView attributes and
+// children, and the selection of each pane of a frozen view.
+func TestSetupView(t *testing.T) {
+	wb, sh := newTestSheet(t, "Data")
+	mustCell(t, sh, "A1").SetStr("x")
+	off := false
+	err := sh.SetupView(ViewOptions{
+		FreezeCols:    1,
+		FreezeRows:    1,
+		TopLeftCell:   "A1",
+		ActiveCell:    "C3",
+		ZoomScale:     150,
+		ViewType:      ViewPageLayout,
+		ShowGridlines: &off,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := writeParts(t, wb).part(t, "/xl/worksheets/sheet1.xml")
+	want := []string{
+		`<sheetView showGridLines="0" topLeftCell="A1" zoomScale="150" view="pageLayout" workbookViewId="0">`,
+		`<pane xSplit="1" ySplit="1" topLeftCell="B2" activePane="bottomRight" state="frozen"/>`,
+		`<selection pane="topRight"/>`,
+		`<selection pane="bottomLeft"/>`,
+		`<selection pane="bottomRight" activeCell="C3" sqref="C3"/>`,
+		`</sheetView>`,
+		`</sheetViews>`,
+		`<sheetData>`,
+	}
+	at := 0
+	for _, w := range want {
+		i := strings.Index(s[at:], w)
+		if i < 0 {
+			t.Fatalf("missing or out of order: %s\n%s", w, s)
+		}
+		at += i + len(w)
+	}
+
+	if err := sh.SetupView(ViewOptions{FreezeRows: 1, ActiveCell: "A1"}); err == nil {
+		t.Error("active cell in a frozen pane accepted")
+	}
+	if err := sh.SetupView(ViewOptions{FreezeRows: 1, ActiveCell: "A2"}); err != nil {
+		t.Fatal(err)
+	}
+	wantContains(t, writeParts(t, wb).part(t, "/xl/worksheets/sheet1.xml"),
+		`<pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/>`+"\n"+
+			`      <selection pane="bottomLeft" activeCell="A2" sqref="A2"/>`)
+}
+
+func TestRightToLeft(t *testing.T) {
+	wb, sh := newTestSheet(t, "Data")
+	mustCell(t, sh, "A1").SetStr("x")
+	if s := writeParts(t, wb).part(t, "/xl/worksheets/sheet1.xml"); strings.Contains(s, "sheetView") {
+		t.Errorf("sheetView written for the default view:\n%s", s)
+	}
+
+	sh.RightToLeft = true
+	wantContains(t, writeParts(t, wb).part(t, "/xl/worksheets/sheet1.xml"),
+		`<sheetView rightToLeft="1" workbookViewId="0"/>`)
+
+	// one sheetView, shared with the panes and the gridlines
+	off := false
+	sh.ShowGridlines = &off
+	sh.FreezeFirstRow()
+	s := writeParts(t, wb).part(t, "/xl/worksheets/sheet1.xml")
+	wantContains(t, s, `<sheetView showGridLines="0" rightToLeft="1" workbookViewId="0">`, `<pane ySplit="1"`)
+	if n := strings.Count(s, "<sheetView "); n != 1 {
+		t.Errorf("got %d sheetView elements, want 1", n)
+	}
+}
@@ -3,26 +3,115 @@ package xl
 import (
 	"errors"
 	"fmt"
+	"slices"
 	"strings"
+	"unicode"
 	"unicode/utf8"
 )
 
 type Workbook struct {
 	AppName string
 	Sheets  []*Sheet
+	Strict  bool // enables additional checks in Validate
 
-	sheetMap map[string]*Sheet
-	lastIdN  int
+	// Company and Manager are written to the extended document properties
+	// (docProps/app.xml) when set.
+	Company string
+	Manager string
+
+	Settings       WorkbookSettings
+	CalcProperties CalcProperties
+	CoreProperties CoreProperties
+
+	// Date1904 switches the workbook to the 1904 date system, which counts
+	// days from 1904-01-01 and is found in files that originate from old
+	// Mac versions of Excel. It applies to all the dates of the workbook,
+	// which then can not be earlier than 1904.
+	Date1904 bool
+
+	// ActiveSheet is the sheet shown when the file is opened, nil selects
+	// the first one. It must be one of Sheets and visible.
+	ActiveSheet *Sheet
+
+	// WindowWidth and WindowHeight are the size of the application window
+	// in twips (1/20 of a point), zero leaves it to Excel.
+	WindowWidth  int
+	WindowHeight int
+
+	sheetMap    map[string]*Sheet
+	lastIdN     int
+	themeColors ThemeColors
+
+	definedNames     []*DefinedName
+	definedNameIndex map[definedNameKey]*DefinedName
+	customProperties []*customProperty
+	namedStyles      []*namedStyle
+	vbaProject       []byte
+}
+
+// WorkbookSettings are the workbook-wide flags written to <workbookPr>.
+type WorkbookSettings struct {
+	ShowObjects string // one of the ShowObjects constants, empty for the default (all)
+	BackupFile  bool   // ask Excel to keep a backup when saving
+	CodeName    string // VBA code name of the workbook
+}
+
+// ShowObjects values control how objects (pictures, charts) are displayed.
+const (
+	ShowObjectsAll          = "all"
+	ShowObjectsPlaceholders = "placeholders"
+	ShowObjectsNone         = "none"
+)
+
+func (ws *WorkbookSettings) validate() error {
+	switch ws.ShowObjects {
+	case "", ShowObjectsAll, ShowObjectsPlaceholders, ShowObjectsNone:
+		return nil
+	}
+	return fmt.Errorf("invalid showObjects value '%s'", ws.ShowObjects)
+}
+
+// RecalcPolicy selects when Excel recalculates the formulas of a workbook.
+type RecalcPolicy int
+
+const (
+	RecalcDefault RecalcPolicy = iota // leave it to Excel
+	RecalcOnLoad                      // full recalculation when the file is opened
+	RecalcOnSave                      // recalculate before saving
+	RecalcManual                      // never recalculate automatically
+)
+
+// CalcProperties controls the calculation settings written to <calcPr>.
+type CalcProperties struct {
+	Recalc RecalcPolicy
+
+	// ForceFullCalc makes every recalculation a full one, rather than only
+	// recalculating the dirty cells.
+	ForceFullCalc bool
+}
+
+func (cp *CalcProperties) validate() error {
+	if cp.Recalc < RecalcDefault || cp.Recalc > RecalcManual {
+		return fmt.Errorf("invalid recalc policy %d", cp.Recalc)
+	}
+	if cp.ForceFullCalc && cp.Recalc == RecalcManual {
+		return errors.New("forced full calculation conflicts with manual recalc")
+	}
+	return nil
 }
 
 func NewWorkbook() *Workbook {
 	return &Workbook{
-		sheetMap: map[string]*Sheet{},
+		sheetMap:         map[string]*Sheet{},
+		themeColors:      DefaultThemeColors,
+		definedNameIndex: map[definedNameKey]*DefinedName{},
 	}
 }
 
+// AddSheet adds a sheet at the end of the workbook. Sheet names are unique
+// regardless of case, as in Excel.
 func (wb *Workbook) AddSheet(name string) (*Sheet, error) {
-	if _, exists := wb.sheetMap[name]; exists {
+	if wb.sheetNameTaken(name, nil) {
 		return nil, fmt.Errorf("duplicate sheet name '%s'", name)
 	}
 
@@ -43,6 +132,196 @@ func (wb *Workbook) AddSheet(name string) (*Sheet, error) {
 	return sheet, nil
 }
 
+// DeleteSheet removes a sheet from the workbook along with the defined
+// names scoped to it. Formulas and links that refer to the sheet by name,
+// e.g. the RefersTo of global defined names, are left as they are.
+func (wb *Workbook) DeleteSheet(name string) error {
+	sh, ok := wb.sheetMap[name]
+	if !ok {
+		return fmt.Errorf("sheet '%s' not found", name)
+	}
+	wb.Sheets = slices.DeleteFunc(wb.Sheets, func(s *Sheet) bool { return s == sh })
+	delete(wb.sheetMap, name)
+	wb.definedNames = slices.DeleteFunc(wb.definedNames, func(dn *DefinedName) bool {
+		if dn.Sheet == sh {
+			delete(wb.definedNameIndex, makeDefinedNameKey(dn.Name, sh))
+			return true
+		}
+		return false
+	})
+	if wb.ActiveSheet == sh {
+		wb.ActiveSheet = nil
+	}
+	return nil
+}
+
+// RenameSheet changes the name of a sheet. The settings of the sheet, such
+// as its print area and scoped defined names, follow the new name, but as
+// with DeleteSheet, formulas that spell out the old name are not updated.
+func (wb *Workbook) RenameSheet(oldName, newName string) error {
+	sh, ok := wb.sheetMap[oldName]
+	if !ok {
+		return fmt.Errorf("sheet '%s' not found", oldName)
+	}
+	if newName == oldName {
+		return nil
+	}
+	// a change of case only is not a duplicate
+	if wb.sheetNameTaken(newName, sh) {
+		return fmt.Errorf("duplicate sheet name '%s'", newName)
+	}
+	if err := validateSheetName(newName); err != nil {
+		return err
+	}
+	delete(wb.sheetMap, oldName)
+	wb.sheetMap[newName] = sh
+	sh.Name = newName
+	return nil
+}
+
+// MoveSheet moves a sheet to the given 0-based position in the tab order,
+// shifting the sheets in between. The ActiveSheet stays the same sheet.
+func (wb *Workbook) MoveSheet(name string, toIndex int) error {
+	sh, ok := wb.sheetMap[name]
+	if !ok {
+		return fmt.Errorf("sheet '%s' not found", name)
+	}
+	if toIndex < 0 || toIndex >= len(wb.Sheets) {
+		return fmt.Errorf("sheet index %d is out of range", toIndex)
+	}
+	i := slices.Index(wb.Sheets, sh)
+	wb.Sheets = slices.Insert(slices.Delete(wb.Sheets, i, i+1), toIndex, sh)
+	return nil
+}
+
+// SwapSheets exchanges the positions of two sheets in the tab order.
+func (wb *Workbook) SwapSheets(a, b string) error {
+	sa, ok := wb.sheetMap[a]
+	if !ok {
+		return fmt.Errorf("sheet '%s' not found", a)
+	}
+	sb, ok := wb.sheetMap[b]
+	if !ok {
+		return fmt.Errorf("sheet '%s' not found", b)
+	}
+	i, j := slices.Index(wb.Sheets, sa), slices.Index(wb.Sheets, sb)
+	wb.Sheets[i], wb.Sheets[j] = sb, sa
+	return nil
+}
+
+// AddSheetUnique adds a sheet, adjusting the name if necessary so that it
+// is valid: characters that are not allowed in sheet names are replaced
+// with underscores, the name is truncated to 31 characters and a numeric
+// suffix is appended if the name is already taken, ignoring case as Excel
+// does. The final name is available in the Name field of the returned
+// sheet, renamed reports whether it differs from the requested one.
+func (wb *Workbook) AddSheetUnique(name string) (sheet *Sheet, renamed bool, err error) {
+	final := wb.uniqueSheetName(sanitizeSheetName(name))
+	sheet, err = wb.AddSheet(final)
+	if err != nil {
+		return nil, false, err
+	}
+	return sheet, final != name, nil
+}
+
+func sanitizeSheetName(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(":\\/?*[]", r) || unicode.IsControl(r) {
+			return '_'
+		}
+		return r
+	}, s)
+	// quotes are trimmed after truncating, which may expose one at the end
+	s = strings.Trim(truncateRunes(s, 31), "'")
+	if s == "" {
+		s = "Sheet"
+	}
+	return s
+}
+
+func (wb *Workbook) uniqueSheetName(s string) string {
+	if !wb.sheetNameTaken(s, nil) {
+		return s
+	}
+	for i := 2; ; i++ {
+		suffix := fmt.Sprintf(" (%d)", i)
+		base := truncateRunes(s, 31-len(suffix))
+		name := base + suffix
+		if !wb.sheetNameTaken(name, nil) {
+			return name
+		}
+	}
+}
+
+// sheetNameTaken reports whether a sheet other than except has the given
+// name, compared case-insensitively as Excel does.
+func (wb *Workbook) sheetNameTaken(name string, except *Sheet) bool {
+	for _, sh := range wb.Sheets {
+		if sh != except && strings.EqualFold(sh.Name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func truncateRunes(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n])
+}
+
+// Validate checks the workbook for problems that would make Excel reject
+// or repair the file: a workbook without sheets or without a visible
+// sheet, invalid or duplicate sheet names, overlapping merged ranges and
+// conflicting calculation settings.
+// All problems found are reported together. When Strict is set,
+// additional checks catch likely programming mistakes, such as merged
+// ranges outside the written data or values hidden by merged ranges.
+//
+// Write calls Validate unless Writer.SkipValidation is set.
+func (wb *Workbook) Validate() error {
+	var errs []error
+	if len(wb.Sheets) == 0 {
+		errs = append(errs, errors.New("the workbook has no sheets"))
+	} else if err := checkSheetVisibility(wb); err != nil {
+		errs = append(errs, err)
+	}
+	if err := wb.CalcProperties.validate(); err != nil {
+		errs = append(errs, err)
+	}
+	names := map[string]bool{}
+	for _, sh := range wb.Sheets {
+		if err := validateSheetName(sh.Name); err != nil {
+			errs = append(errs, fmt.Errorf("sheet '%s': %w", sh.Name, err))
+		}
+		// Excel compares sheet names case-insensitively
+		key := strings.ToUpper(sh.Name)
+		if names[key] {
+			errs = append(errs, fmt.Errorf("duplicate sheet name '%s'", sh.Name))
+		}
+		names[key] = true
+		if err := sh.validateMerges(); err != nil {
+			errs = append(errs, err)
+		}
+		if wb.Strict {
+			if err := sh.validateMergesStrict(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// SetVBAProject embeds a compiled VBA project (vbaProject.bin, as
+// extracted from an existing .xlsm file), which turns the output into a
+// macro-enabled workbook. Such files must be saved with the .xlsm
+// extension, Excel refuses to open them as .xlsx. Passing nil removes the
+// project.
+func (wb *Workbook) SetVBAProject(blob []byte) {
+	wb.vbaProject = blob
+}
+
 func validateSheetName(s string) error {
 	n := utf8.RuneCountInString(s)
 	if n == 0 {
@@ -56,5 +335,10 @@ func validateSheetName(s string) error {
 	if strings.ContainsAny(s, ":\\/?*[]") {
 		return errors.New("the sheet can not contain any of the characters :\\/?*[]")
 	}
+	// other characters, including &<>", are escaped when the name is
+	// written, control characters can not be represented in XML at all
+	if strings.IndexFunc(s, unicode.IsControl) >= 0 {
+		return errors.New("the sheet name can not contain control characters")
+	}
 	return nil
 }
@@ -0,0 +1,174 @@
+package xl
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestAddSheetUnique(t *testing.T) {
+	wb := NewWorkbook()
+	for _, tc := range []struct {
+		name    string
+		want    string
+		renamed bool
+	}{
+		{"Data", "Data", false},
+		{"DATA", "DATA (2)", true},
+		{"a/b", "a_b", true},
+		{"'quoted'", "quoted", true},
+		{"''", "Sheet", true},
+		// the quote only becomes the last character after truncation
+		{strings.Repeat("a", 30) + "'b", strings.Repeat("a", 30), true},
+	} {
+		sh, renamed, err := wb.AddSheetUnique(tc.name)
+		if err != nil {
+			t.Errorf("AddSheetUnique(%q): %v", tc.name, err)
+			continue
+		}
+		if sh.Name != tc.want || renamed != tc.renamed {
+			t.Errorf("AddSheetUnique(%q) = %q, %v; want %q, %v", tc.name, sh.Name, renamed, tc.want, tc.renamed)
+		}
+	}
+	if err := wb.Validate(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCalcPropertiesBeforeWrite(t *testing.T) {
+	wb, _ := newTestSheet(t, "Sheet1")
+	wb.CalcProperties = CalcProperties{Recalc: RecalcManual, ForceFullCalc: true}
+	if err := wb.Validate(); err == nil {
+		t.Error("Validate accepted conflicting calc properties")
+	}
+	m := memStorage{}
+	w := NewWriter(m)
+	w.SkipValidation = true
+	if err := w.Write(wb); err == nil {
+		t.Error("Write accepted conflicting calc properties")
+	}
+	if len(m) != 0 {
+		t.Errorf("%d parts written before the error", len(m))
+	}
+
+	wb.CalcProperties = CalcProperties{Recalc: RecalcOnSave}
+	wantContains(t, writeParts(t, wb).part(t, "/xl/workbook.xml"), `<calcPr calcOnSave="1"/>`)
+}
+
+func TestSheetNamesIgnoreCase(t *testing.T) {
+	wb := NewWorkbook()
+	for _, name := range []string{"A", "B"} {
+		if _, err := wb.AddSheet(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := wb.AddSheet("b"); err == nil {
+		t.Error("AddSheet accepted 'b' next to 'B'")
+	}
+	if err := wb.RenameSheet("A", "b"); err == nil {
+		t.Error("RenameSheet accepted 'b' next to 'B'")
+	}
+	if err := wb.RenameSheet("A", "a"); err != nil {
+		t.Errorf("RenameSheet to a different case: %v", err)
+	}
+	if wb.Sheets[0].Name != "a" {
+		t.Errorf("got %q, want 'a'", wb.Sheets[0].Name)
+	}
+	if err := wb.Validate(); err != nil {
+		t.Error(err)
+	}
+}
+
+// TestSheetPartNames checks that the worksheet parts are named by position,
+// whatever the sheet names, and that the names survive a round trip.
+func TestSheetPartNames(t *testing.T) {
+	wb := NewWorkbook()
+	if _, err := wb.AddSheet("Revenue/Cost"); err == nil {
+		t.Error("AddSheet accepted a name with a slash")
+	}
+	var names []string
+	for _, name := range []string{"Q1 2024", "Revenue/Cost", "Résumé"} {
+		sh, _, err := wb.AddSheetUnique(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		mustCell(t, sh, "A1").SetStr(name)
+		names = append(names, sh.Name)
+	}
+	if names[1] != "Revenue_Cost" {
+		t.Errorf("got %s, want Revenue_Cost", names[1])
+	}
+
+	m := writeParts(t, wb)
+	for i := 1; i <= 3; i++ {
+		m.part(t, fmt.Sprintf("/xl/worksheets/sheet%d.xml", i))
+	}
+	wantContains(t, m.part(t, "/xl/_rels/workbook.xml.rels"),
+		`Target="worksheets/sheet1.xml"`, `Target="worksheets/sheet2.xml"`, `Target="worksheets/sheet3.xml"`)
+	wantContains(t, m.part(t, "[Content_Types].xml"),
+		`PartName="/xl/worksheets/sheet1.xml"`, `PartName="/xl/worksheets/sheet3.xml"`)
+	wantContains(t, m.part(t, "/xl/workbook.xml"),
+		`<sheet name="Q1 2024" sheetId="1"`, `<sheet name="Revenue_Cost" sheetId="2"`, `<sheet name="Résumé" sheetId="3"`)
+
+	rwb := roundTrip(t, wb)
+	for i, sh := range rwb.Sheets {
+		if sh.Name != names[i] || sh.Rows[0].Cells[0].Value() != []string{"Q1 2024", "Revenue/Cost", "Résumé"}[i] {
+			t.Errorf("sheet %d: got %s %q", i+1, sh.Name, sh.Rows[0].Cells[0].Value())
+		}
+	}
+	if len(rwb.Sheets) != 3 {
+		t.Errorf("got %d sheets, want 3", len(rwb.Sheets))
+	}
+}
+
+func TestSheetNameEscaping(t *testing.T) {
+	wb := newTestSheets(t, "A & B <C>", `Say "hi"`)
+	if _, err := wb.AddSheet("Tab\tName"); err == nil {
+		t.Error("AddSheet accepted a control character")
+	}
+	sh, _, err := wb.AddSheetUnique("Tab\tName")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sh.Name != "Tab_Name" {
+		t.Errorf("AddSheetUnique: got %q, want Tab_Name", sh.Name)
+	}
+	// writeParts checks that the parts are well-formed
+	wantContains(t, writeParts(t, wb).part(t, "/xl/workbook.xml"),
+		`<sheet name="A &amp; B &lt;C&gt;" sheetId="1"`, `<sheet name="Say &quot;hi&quot;" sheetId="2"`)
+}
+
+// TestWorkbookRelsOrder uses enough sheets for the workbook relationship
+// ids to reach two digits, they must be listed in numeric order.
+func TestWorkbookRelsOrder(t *testing.T) {
+	wb := NewWorkbook()
+	const n = 14
+	for i := 1; i <= n; i++ {
+		sh, err := wb.AddSheet(fmt.Sprint("S", i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		mustCell(t, sh, "A1").SetStr("x")
+	}
+	m := writeParts(t, wb)
+	ids := regexp.MustCompile(`Id="rId(\d+)"`).FindAllStringSubmatch(m.part(t, "/xl/_rels/workbook.xml.rels"), -1)
+	if len(ids) <= n {
+		t.Fatalf("got %d relationships, want more than %d", len(ids), n)
+	}
+	for i, id := range ids {
+		if id[1] != strconv.Itoa(i+1) {
+			t.Fatalf("relationship %d is rId%s, want rId%d", i, id[1], i+1)
+		}
+	}
+	book := m.part(t, "/xl/workbook.xml")
+	rels := m.part(t, "/xl/_rels/workbook.xml.rels")
+	for i := 1; i <= n; i++ {
+		sm := regexp.MustCompile(fmt.Sprintf(`<sheet name="S%d" sheetId="\d+" r:id="(rId\d+)"/>`, i)).FindStringSubmatch(book)
+		if sm == nil {
+			t.Fatalf("sheet S%d missing", i)
+		}
+		wantContains(t, rels, fmt.Sprintf(`Id="%s" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet%d.xml"`, sm[1], i))
+	}
+}
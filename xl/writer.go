@@ -5,8 +5,10 @@ import (
 	"errors"
 	"fmt"
 	"slices"
+	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/adnsv/srw/xml"
 
@@ -15,41 +17,112 @@ import (
 )
 
 type Writer struct {
+	// XMLConfig controls the formatting of the generated XML parts. Every
+	// part starts with the same declaration, regardless of the config:
+	// <?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+	XMLConfig xml.WriterConfig
+
+	// PartIndentPolicy selects the parts that XMLConfig applies to.
+	PartIndentPolicy PartIndentPolicy
+
 	out            Storage
 	lastGlobalId   int
 	lastWorkbookId int
 	lastRichDataId int
+	lastCommentsId int
+	lastShapeBlock int // of the vml shape ids, see writeCommentsVML
 
 	GlobalRels          map[string]RelInfo // maps id to absolute path
 	WorkbookRels        map[string]RelInfo // maps id to absolute paths
 	DefaultContentTypes map[string]string  // maps path extension to content-type
 	PartContentTypes    map[string]string  // maps path partname to content-type
 
-	sharedStrings   []string
-	sharedStringMap map[string]int // 1-based index into sharedStrings
-
-	media    []*MediaInfo
-	mediaMap map[string]*MediaInfo // maps media name to media info
-
-	xfs []*XF
+	sharedStrings    []string
+	sharedStringMap  map[string]int // 1-based index into sharedStrings
+	sharedStringRefs int            // total number of cells referencing shared strings
+
+	// SkipValidation keeps Write from calling Workbook.Validate, e.g. to
+	// save the time for workbooks that are known to be valid. The checks
+	// that prevent a corrupt package are still performed.
+	SkipValidation bool
+
+	// RejectNonFinite makes Write fail on cells that were given NaN or ±Inf,
+	// rather than writing them as #NUM! and #DIV/0! errors.
+	RejectNonFinite bool
+
+	// InlineStrings writes the strings set with Cell.SetStr into the cells
+	// themselves, like Cell.SetInlineStr, instead of the shared string
+	// table, which is then omitted unless cells refer to it by index. This
+	// saves the time and memory spent on building the table, at the cost of
+	// a larger file when strings repeat; it suits one-off exports that are
+	// not edited afterwards.
+	InlineStrings bool
+
+	// EscapeControlChars selects how the characters of cell strings that
+	// are not allowed in XML (control characters other than tab, newline
+	// and carriage return) are written. By default they are dropped, when
+	// set they are written as _xHHHH_ escapes, which Excel turns back into
+	// the original characters. Invalid UTF-8 is always replaced with
+	// U+FFFD.
+	EscapeControlChars bool
+
+	// OmitSharedStringCounts skips the optional count and uniqueCount
+	// attributes of the shared string table.
+	OmitSharedStringCounts bool
+
+	media         []*MediaInfo
+	mediaMap      map[string]*MediaInfo // maps media name to media info
+	valueMetadata []valueMetadata       // referenced from cells by the 1-based vm attribute
+
+	xfs         []*XF
+	fonts       []*Font       // index 0 is the default font
+	fills       []*Fill       // index 0 and 1 are the fills required by Excel
+	borders     []*Border     // index 0 is the empty border
+	numFmts     []string      // custom number format codes, ids start at 164
+	namedStyles []*namedStyle // of the workbook being written
+	dxfs        []*DiffFormat // differential formats of conditional formatting
 
 	RichDataRels map[string]RelInfo
 }
 
+// PartIndentPolicy selects which parts are formatted with the indentation
+// from Writer.XMLConfig.
+type PartIndentPolicy int
+
+const (
+	// IndentAllParts formats all parts the same way.
+	IndentAllParts PartIndentPolicy = iota
+
+	// IndentSmallParts formats the small structural parts (workbook,
+	// styles, relationships, metadata) with XMLConfig, which is handy for
+	// inspecting the output with DirStorage, while the bulk data parts
+	// (worksheets and shared strings) are written without indentation.
+	IndentSmallParts
+)
+
 type RelInfo struct {
-	Type   string // url to schema type
-	Target string // relative path
+	Type       string // url to schema type
+	Target     string // relative path, or an absolute url for external targets
+	TargetMode string // empty for internal targets, or "External"
 }
 
 type MediaInfo struct {
 	Name string // hashed blob + extension
 	Blob []byte
-	IId  int
-	RId  string
+	IId  int    // rich value index
+	RId  string // rich value relationship id
+	VM   int    // 1-based index of the valueMetadata entry
+}
+
+// valueMetadata is an entry of the valueMetadata section of the metadata
+// part, which attaches a rich value to the cells that refer to it.
+type valueMetadata struct {
+	richValue int // index into the rich value data
 }
 
 func NewWriter(s Storage) *Writer {
 	w := &Writer{
+		XMLConfig:           xml.WriterConfig{Indent: xml.Indent2Spaces},
 		out:                 s,
 		GlobalRels:          map[string]RelInfo{},
 		WorkbookRels:        map[string]RelInfo{},
@@ -79,27 +152,191 @@ func (w *Writer) SharedString(s string) int {
 	return i
 }
 
+// InternStrings adds strings to the shared string table ahead of writing
+// and returns their indices, suitable for Cell.SetSharedIndex.
+func (w *Writer) InternStrings(ss []string) []int {
+	ii := make([]int, len(ss))
+	for n, s := range ss {
+		ii[n] = w.SharedString(s)
+	}
+	return ii
+}
+
+// checkSharedIndices verifies the cells set with Cell.SetSharedIndex
+// against the strings interned so far. The strings of the other cells are
+// added to the table while the sheets are written, an index must not
+// depend on them.
+func (w *Writer) checkSharedIndices(wb *Workbook) error {
+	for _, sh := range wb.Sheets {
+		for _, r := range sh.Rows {
+			for _, c := range r.Cells {
+				if c.typ != cellTypeSharedIndex {
+					continue
+				}
+				i, err := strconv.Atoi(c.v)
+				if err != nil || i < 0 || i >= len(w.sharedStrings) {
+					return fmt.Errorf("sheet '%s', cell %s: shared string index %s is out of range", sh.Name, c.coord, c.v)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// collectStyles registers the cell styles of all sheets in the order
+// writeSheet uses them, so that their alignment and number, as well as
+// the alignment of the named styles, are checked before any part is
+// written.
+func (w *Writer) collectStyles(wb *Workbook) error {
+	add := func(xf XF) error {
+		if err := xf.Alignment.validate(); err != nil {
+			return err
+		}
+		w.styleIndex(&xf)
+		return nil
+	}
+	for _, ns := range wb.namedStyles {
+		if err := ns.xf.Alignment.validate(); err != nil {
+			return fmt.Errorf("cell style '%s': %w", ns.name, err)
+		}
+	}
+	for _, sh := range wb.Sheets {
+		if sh.RawData {
+			continue
+		}
+		err := enumerate(sh.Columns, func(n int, v *Column) error {
+			if v.Style.Empty() {
+				return nil
+			}
+			xf, err := sh.workbook.resolveStyle(v.Style)
+			if err == nil {
+				err = add(xf)
+			}
+			if err != nil {
+				return fmt.Errorf("sheet '%s', column %d: %w", sh.Name, n, err)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, r := range sh.Rows {
+			for _, c := range r.Cells {
+				xf := cellXF(sh, c)
+				_, err := sh.workbook.resolveStyle(xf)
+				if err == nil && !xf.Empty() {
+					err = add(xf)
+				}
+				if err != nil {
+					return fmt.Errorf("sheet '%s', cell %s: %w", sh.Name, c.coord, err)
+				}
+			}
+		}
+	}
+	if len(w.xfs) > MaxCellStyles {
+		return fmt.Errorf("the number of unique cell styles exceeds the limit of %d", MaxCellStyles)
+	}
+	return nil
+}
+
+// SharedStringStats reports the number of unique strings in the shared
+// string table and the total number of cell references to them. The
+// figures are complete once the workbook has been written.
+func (w *Writer) SharedStringStats() (unique, total int) {
+	return len(w.sharedStrings), w.sharedStringRefs
+}
+
+// newXML starts the XML part at path in bb.
+func (w *Writer) newXML(bb *bytes.Buffer, path string) *xml.Writer {
+	x := xml.NewWriter(bb, w.partXMLConfig(path))
+	x.XmlStandaloneDecl()
+	return x
+}
+
+// partXMLConfig selects the XML formatting of a part according to the
+// PartIndentPolicy.
+func (w *Writer) partXMLConfig(path string) xml.WriterConfig {
+	if w.PartIndentPolicy == IndentSmallParts && isBulkPart(path) {
+		return xml.WriterConfig{Indent: xml.IndentNone}
+	}
+	return w.XMLConfig
+}
+
+// isBulkPart reports whether the part holds the bulk of the workbook data
+func isBulkPart(path string) bool {
+	return strings.HasPrefix(path, "/xl/worksheets/") && strings.HasSuffix(path, ".xml") ||
+		path == "/xl/sharedStrings.xml"
+}
+
 func (w *Writer) nextGlobalID() (int, string) {
 	w.lastGlobalId++
-	return w.lastGlobalId, fmt.Sprintf("rId%d", w.lastGlobalId)
+	return w.lastGlobalId, relID(w.lastGlobalId)
 }
 func (w *Writer) nextWorkbookID() (int, string) {
 	w.lastWorkbookId++
-	return w.lastWorkbookId, fmt.Sprintf("rId%d", w.lastWorkbookId)
+	return w.lastWorkbookId, relID(w.lastWorkbookId)
 }
 func (w *Writer) nextRichDataID() (int, string) {
 	w.lastRichDataId++
-	return w.lastRichDataId, fmt.Sprintf("rId%d", w.lastRichDataId)
+	return w.lastRichDataId, relID(w.lastRichDataId)
 }
 
 func (w *Writer) Write(wb *Workbook) error {
 	var err error
+	w.namedStyles = wb.namedStyles
+
+	if !w.SkipValidation {
+		err = wb.Validate()
+		if err != nil {
+			return err
+		}
+	}
+
+	// fail before anything is written to the storage
+	err = validatePictures(wb)
+	if err != nil {
+		return err
+	}
+	err = checkSheetVisibility(wb)
+	if err != nil {
+		return err
+	}
+	err = wb.CalcProperties.validate()
+	if err != nil {
+		return err
+	}
+	for _, sh := range wb.Sheets {
+		err = checkSheetLimits(sh)
+		if err != nil {
+			return err
+		}
+	}
+	err = w.checkSharedIndices(wb)
+	if err != nil {
+		return err
+	}
+	err = w.collectStyles(wb)
+	if err != nil {
+		return err
+	}
 
 	err = w.writeWorkbook(wb)
 	if err != nil {
 		return err
 	}
 
+	err = w.writeTheme(&wb.themeColors)
+	if err != nil {
+		return err
+	}
+
+	if len(wb.vbaProject) > 0 {
+		err = w.writeVBAProject(wb.vbaProject)
+		if err != nil {
+			return err
+		}
+	}
+
 	if len(w.media) > 0 {
 
 		err = w.writeMedia()
@@ -133,21 +370,29 @@ func (w *Writer) Write(wb *Workbook) error {
 		if err != nil {
 			return err
 		}
+	}
 
+	if len(w.valueMetadata) > 0 {
 		err = w.writeMetadata()
 		if err != nil {
 			return err
 		}
 	}
 
-	err = w.writeCoreProperties()
+	err = w.writeCoreProperties(&wb.CoreProperties)
 	if err != nil {
 		return err
 	}
-	err = w.writeExtendedProperties(wb.AppName)
+	err = w.writeExtendedProperties(wb)
 	if err != nil {
 		return err
 	}
+	if len(wb.customProperties) > 0 {
+		err = w.writeCustomProperties(wb.customProperties)
+		if err != nil {
+			return err
+		}
+	}
 
 	if len(w.sharedStrings) > 0 {
 		err = w.writeSharedStrings()
@@ -156,7 +401,9 @@ func (w *Writer) Write(wb *Workbook) error {
 		}
 	}
 
-	if len(w.xfs) > 0 {
+	// styles are collected while the worksheets are written, so this
+	// must follow writeWorkbook
+	if len(w.xfs) > 0 || len(w.namedStyles) > 0 || len(w.dxfs) > 0 {
 		err = w.writeStyles()
 		if err != nil {
 			return err
@@ -181,7 +428,7 @@ func (w *Writer) Write(wb *Workbook) error {
 	return nil
 }
 
-func (w *Writer) writeCoreProperties() error {
+func (w *Writer) writeCoreProperties(props *CoreProperties) error {
 	_, rid := w.nextGlobalID()
 
 	relpath := "docProps/core.xml"
@@ -194,9 +441,8 @@ func (w *Writer) writeCoreProperties() error {
 	}
 
 	bb := bytes.Buffer{}
-	x := xml.NewWriter(&bb, xml.WriterConfig{Indent: xml.Indent2Spaces})
+	x := w.newXML(&bb, abspath)
 
-	x.XmlStandaloneDecl()
 	x.OTag("cp:coreProperties")
 	x.Attr("xmlns:cp", "http://schemas.openxmlformats.org/package/2006/metadata/core-properties")
 	x.Attr("xmlns:dc", "http://purl.org/dc/elements/1.1/")
@@ -204,17 +450,46 @@ func (w *Writer) writeCoreProperties() error {
 	x.Attr("xmlns:dcmitype", "http://purl.org/dc/dcmitype/")
 	x.Attr("xmlns:xsi", "http://www.w3.org/2001/XMLSchema-instance")
 
-	x.OTag("+dcterms:created")
-	x.Attr("xsi:type", "dcterms:W3CDTF")
-	x.Write(time.Now().UTC().Format(time.RFC3339))
-	x.CTag()
+	for _, p := range []struct {
+		tag   xml.NameString
+		value string
+	}{
+		{"dc:title", props.Title},
+		{"dc:subject", props.Subject},
+		{"dc:creator", props.Creator},
+		{"cp:keywords", props.Keywords},
+		{"dc:description", props.Description},
+		{"cp:lastModifiedBy", props.LastModifiedBy},
+	} {
+		if p.value != "" {
+			x.OTag("+" + p.tag).String(sanitizePackageText(p.value)).CTag()
+		}
+	}
+
+	now := time.Now()
+	for _, p := range []struct {
+		tag   xml.NameString
+		value time.Time
+	}{
+		{"dcterms:created", props.Created},
+		{"dcterms:modified", props.Modified},
+	} {
+		t := p.value
+		if t.IsZero() {
+			t = now
+		}
+		x.OTag("+" + p.tag)
+		x.Attr("xsi:type", "dcterms:W3CDTF")
+		x.Write(t.UTC().Format(time.RFC3339))
+		x.CTag()
+	}
 
 	x.CTag()
 
 	return w.out.WriteBlob(abspath, bb.Bytes())
 }
 
-func (w *Writer) writeExtendedProperties(appname string) error {
+func (w *Writer) writeExtendedProperties(wb *Workbook) error {
 	_, rid := w.nextGlobalID()
 
 	relpath := "docProps/app.xml"
@@ -227,15 +502,74 @@ func (w *Writer) writeExtendedProperties(appname string) error {
 	}
 
 	bb := bytes.Buffer{}
-	x := xml.NewWriter(&bb, xml.WriterConfig{Indent: xml.Indent2Spaces})
-	x.XmlStandaloneDecl()
+	x := w.newXML(&bb, abspath)
 
 	x.OTag("Properties")
 	x.Attr("xmlns", "http://schemas.openxmlformats.org/officeDocument/2006/extended-properties")
 	x.Attr("xmlns:vt", "http://schemas.openxmlformats.org/officeDocument/2006/docPropsVTypes")
 
-	if appname != "" {
-		x.OTag("+Application").String(appname).CTag()
+	if wb.AppName != "" {
+		x.OTag("+Application").String(sanitizePackageText(wb.AppName)).CTag()
+	}
+
+	// the parts of the document by category, Excel lists the worksheets
+	if len(wb.Sheets) > 0 {
+		x.OTag("+HeadingPairs")
+		x.OTag("+vt:vector").Attr("size", 2).Attr("baseType", "variant")
+		x.OTag("+vt:variant").OTag("vt:lpstr").String("Worksheets").CTag().CTag()
+		x.OTag("+vt:variant").OTag("vt:i4").Write(len(wb.Sheets)).CTag().CTag()
+		x.CTag() // vt:vector
+		x.CTag() // HeadingPairs
+
+		x.OTag("+TitlesOfParts")
+		x.OTag("+vt:vector").Attr("size", len(wb.Sheets)).Attr("baseType", "lpstr")
+		for _, sh := range wb.Sheets {
+			x.OTag("+vt:lpstr").String(sh.Name).CTag()
+		}
+		x.CTag() // vt:vector
+		x.CTag() // TitlesOfParts
+	}
+
+	if wb.Manager != "" {
+		x.OTag("+Manager").String(sanitizePackageText(wb.Manager)).CTag()
+	}
+	if wb.Company != "" {
+		x.OTag("+Company").String(sanitizePackageText(wb.Company)).CTag()
+	}
+
+	x.CTag()
+
+	return w.out.WriteBlob(abspath, bb.Bytes())
+}
+
+func (w *Writer) writeCustomProperties(props []*customProperty) error {
+	_, rid := w.nextGlobalID()
+
+	relpath := "docProps/custom.xml"
+	abspath := "/" + relpath
+
+	w.PartContentTypes[abspath] = "application/vnd.openxmlformats-officedocument.custom-properties+xml"
+	w.GlobalRels[rid] = RelInfo{
+		Type:   "http://schemas.openxmlformats.org/officeDocument/2006/relationships/custom-properties",
+		Target: relpath,
+	}
+
+	bb := bytes.Buffer{}
+	x := w.newXML(&bb, abspath)
+
+	x.OTag("Properties")
+	x.Attr("xmlns", "http://schemas.openxmlformats.org/officeDocument/2006/custom-properties")
+	x.Attr("xmlns:vt", "http://schemas.openxmlformats.org/officeDocument/2006/docPropsVTypes")
+
+	for i, p := range props {
+		// pids 0 and 1 are reserved, user properties start at 2
+		x.OTag("+property")
+		x.Attr("fmtid", "{D5CDD505-2E9C-101B-9397-08002B2CF9AE}")
+		x.Attr("pid", i+2)
+		x.Attr("name", sanitizePackageText(p.name))
+		tag, v := p.vtValue()
+		x.OTag(xml.NameString(tag)).String(sanitizePackageText(v)).CTag()
+		x.CTag()
 	}
 
 	x.CTag()
@@ -245,9 +579,8 @@ func (w *Writer) writeExtendedProperties(appname string) error {
 
 func (w *Writer) writeContentTypes() error {
 	bb := bytes.Buffer{}
-	x := xml.NewWriter(&bb, xml.WriterConfig{Indent: xml.Indent2Spaces})
+	x := w.newXML(&bb, "/[Content_Types].xml")
 
-	x.XmlStandaloneDecl()
 	x.OTag("Types")
 	x.Attr("xmlns", "http://schemas.openxmlformats.org/package/2006/content-types")
 	enumerate(w.DefaultContentTypes, func(ext, ctype string) error {
@@ -264,6 +597,55 @@ func (w *Writer) writeContentTypes() error {
 	return w.out.WriteBlob("[Content_Types].xml", bb.Bytes())
 }
 
+// xfComponentIDs holds the indices of the number format, font, fill and
+// border of a cell format, zero selects the default.
+type xfComponentIDs struct {
+	numFmt int
+	font   int
+	fill   int
+	border int
+}
+
+// xfComponentIDs resolves the components of a cell format, registering
+// them as needed.
+func (w *Writer) xfComponentIDs(xf *XF) xfComponentIDs {
+	return xfComponentIDs{
+		numFmt: w.numFmtID(xf.NumFmt),
+		font:   w.fontID(&xf.Font),
+		fill:   w.fillID(&xf.Fill),
+		border: w.borderID(&xf.Border),
+	}
+}
+
+// writeXFChildren writes the alignment and protection of a cell format
+// along with their apply flags, which are set when they differ from the
+// parent style.
+func writeXFChildren(x *xml.Writer, xf, parent *XF) error {
+	if xf.Alignment != parent.Alignment {
+		x.Attr("applyAlignment", 1)
+	}
+	if xf.Protection != parent.Protection {
+		x.Attr("applyProtection", 1)
+	}
+	if !xf.Alignment.Empty() {
+		err := writeAlignment(x, &xf.Alignment)
+		if err != nil {
+			return err
+		}
+	}
+	if !xf.Protection.Empty() {
+		x.OTag("protection")
+		if xf.Protection.Unlocked {
+			x.Attr("locked", 0)
+		}
+		if xf.Protection.Hidden {
+			x.Attr("hidden", 1)
+		}
+		x.CTag()
+	}
+	return nil
+}
+
 func (w *Writer) writeStyles() error {
 	_, rid := w.nextWorkbookID()
 
@@ -277,65 +659,266 @@ func (w *Writer) writeStyles() error {
 	}
 
 	bb := bytes.Buffer{}
-	x := xml.NewWriter(&bb, xml.WriterConfig{Indent: xml.Indent2Spaces})
-	x.XmlStandaloneDecl()
+	x := w.newXML(&bb, abspath)
 
 	x.OTag("styleSheet")
 	x.Attr("xmlns", "http://schemas.openxmlformats.org/spreadsheetml/2006/main")
 
-	x.OTag("fonts").Attr("count", 1)
-	x.OTag("font").CTag()
+	if len(w.xfs) == 0 {
+		w.xfs = append(w.xfs, &XF{})
+	}
+
+	// resolve the components of the cell formats and the named styles
+	// first, their sections precede cellXfs; the parent of a cell format
+	// is Normal (0) or the named style it refers to
+	styleXFs := []*XF{{}}
+	styleXFIndex := map[string]int{}
+	for _, ns := range w.namedStyles {
+		styleXFIndex[strings.ToLower(ns.name)] = len(styleXFs)
+		styleXFs = append(styleXFs, &ns.xf)
+	}
+	styleIDs := make([]xfComponentIDs, len(styleXFs))
+	for i, xf := range styleXFs {
+		styleIDs[i] = w.xfComponentIDs(xf)
+	}
+	ids := make([]xfComponentIDs, len(w.xfs))
+	for i, xf := range w.xfs {
+		ids[i] = w.xfComponentIDs(xf)
+	}
+	if len(w.numFmts) > 0 {
+		x.OTag("+numFmts").Attr("count", len(w.numFmts))
+		for i, code := range w.numFmts {
+			x.OTag("+numFmt").Attr("numFmtId", 164+i).Attr("formatCode", w.sanitizeText(code)).CTag()
+		}
+		x.CTag()
+	}
+
+	x.OTag("+fonts").Attr("count", len(w.fonts))
+	for _, f := range w.fonts {
+		err := writeFont(x, f)
+		if err != nil {
+			return err
+		}
+	}
 	x.CTag() // fonts
 
-	x.OTag("fills").Attr("count", 1)
-	x.OTag("fill")
-	x.OTag("patternFill").Attr("patternType", "none").CTag()
-	x.CTag() // fill
+	x.OTag("+fills").Attr("count", len(w.fills))
+	for _, f := range w.fills {
+		err := writeFill(x, f)
+		if err != nil {
+			return err
+		}
+	}
 	x.CTag() // fills
 
-	x.OTag("borders").Attr("count", 1)
-	x.OTag("border")
-	x.OTag("left").CTag()
-	x.OTag("top").CTag()
-	x.OTag("right").CTag()
-	x.OTag("bottom").CTag()
-	x.OTag("diagonal").CTag()
-	x.CTag() // border
+	x.OTag("+borders").Attr("count", len(w.borders))
+	for i, b := range w.borders {
+		if i == 0 {
+			x.OTag("+border")
+			x.OTag("left").CTag()
+			x.OTag("right").CTag()
+			x.OTag("top").CTag()
+			x.OTag("bottom").CTag()
+			x.OTag("diagonal").CTag()
+			x.CTag() // border
+			continue
+		}
+		err := writeBorder(x, b)
+		if err != nil {
+			return err
+		}
+	}
 	x.CTag() // borders
 
-	x.OTag("cellStyleXfs").Attr("count", 1)
-	x.OTag("xf")
-	x.Attr("numFmtId", 0)
-	x.Attr("fontId", 0)
-	x.Attr("fillId", 0)
-	x.Attr("borderId", 0)
-	x.CTag()
+	x.OTag("+cellStyleXfs").Attr("count", len(styleXFs))
+	for i, xf := range styleXFs {
+		x.OTag("+xf")
+		x.Attr("numFmtId", styleIDs[i].numFmt)
+		x.Attr("fontId", styleIDs[i].font)
+		x.Attr("fillId", styleIDs[i].fill)
+		x.Attr("borderId", styleIDs[i].border)
+		err := writeXFChildren(x, xf, &XF{})
+		if err != nil {
+			return err
+		}
+		x.CTag() // xf
+	}
 	x.CTag() //cellStyleXfs
 
-	x.OTag("cellXfs")
-	// TODO: finish this
-	x.CTag()
+	x.OTag("+cellXfs").Attr("count", len(w.xfs))
+	for i, xf := range w.xfs {
+		parent := 0
+		if xf.Style != "" {
+			parent = styleXFIndex[strings.ToLower(xf.Style)]
+		}
+		x.OTag("+xf")
+		x.Attr("numFmtId", ids[i].numFmt)
+		x.Attr("fontId", ids[i].font)
+		x.Attr("fillId", ids[i].fill)
+		x.Attr("borderId", ids[i].border)
+		x.Attr("xfId", parent)
+		// the apply flags tell Excel that the component overrides the one
+		// of the parent cell style, some versions ignore it otherwise
+		if ids[i].numFmt != styleIDs[parent].numFmt {
+			x.Attr("applyNumberFormat", 1)
+		}
+		if ids[i].font != styleIDs[parent].font {
+			x.Attr("applyFont", 1)
+		}
+		if ids[i].fill != styleIDs[parent].fill {
+			x.Attr("applyFill", 1)
+		}
+		if ids[i].border != styleIDs[parent].border {
+			x.Attr("applyBorder", 1)
+		}
+		err := writeXFChildren(x, xf, styleXFs[parent])
+		if err != nil {
+			return err
+		}
+		x.CTag() // xf
+	}
+	x.CTag() // cellXfs
+
+	x.OTag("+cellStyles").Attr("count", len(styleXFs))
+	x.OTag("+cellStyle").Attr("name", "Normal").Attr("xfId", 0).Attr("builtinId", 0).CTag()
+	for _, ns := range w.namedStyles {
+		x.OTag("+cellStyle").Attr("name", w.sanitizeText(ns.name)).Attr("xfId", styleXFIndex[strings.ToLower(ns.name)]).CTag()
+	}
+	x.CTag() // cellStyles
+
+	if len(w.dxfs) > 0 {
+		x.OTag("+dxfs").Attr("count", len(w.dxfs))
+		for _, f := range w.dxfs {
+			w.writeDxf(x, f)
+		}
+		x.CTag() // dxfs
+	}
 
 	x.CTag()
 
 	return w.out.WriteBlob(abspath, bb.Bytes())
 }
 
+func (w *Writer) writeTheme(colors *ThemeColors) error {
+	_, rid := w.nextWorkbookID()
+
+	relpath := "theme/theme1.xml"
+	abspath := "/xl/" + relpath
+
+	w.PartContentTypes[abspath] = "application/vnd.openxmlformats-officedocument.theme+xml"
+	w.WorkbookRels[rid] = RelInfo{
+		Type:   "http://schemas.openxmlformats.org/officeDocument/2006/relationships/theme",
+		Target: relpath,
+	}
+
+	bb := bytes.Buffer{}
+	x := w.newXML(&bb, abspath)
+
+	x.OTag("a:theme")
+	x.Attr("xmlns:a", "http://schemas.openxmlformats.org/drawingml/2006/main")
+	x.Attr("name", "Office Theme")
+
+	x.OTag("+a:themeElements")
+
+	x.OTag("+a:clrScheme").Attr("name", "Office")
+	for i, p := range colors.slots() {
+		x.OTag("+a:" + xml.NameString(themeColorNames[i]))
+		x.OTag("a:srgbClr").Attr("val", *p).CTag()
+		x.CTag()
+	}
+	x.CTag() // clrScheme
+
+	x.OTag("+a:fontScheme").Attr("name", "Office")
+	for _, f := range []struct {
+		tag      xml.NameString
+		typeface string
+	}{{"+a:majorFont", "Calibri Light"}, {"+a:minorFont", "Calibri"}} {
+		x.OTag(f.tag)
+		x.OTag("+a:latin").Attr("typeface", f.typeface).CTag()
+		x.OTag("+a:ea").Attr("typeface", "").CTag()
+		x.OTag("+a:cs").Attr("typeface", "").CTag()
+		x.CTag()
+	}
+	x.CTag() // fontScheme
+
+	x.OTag("+a:fmtScheme").Attr("name", "Office")
+	x.OTag("+a:fillStyleLst")
+	for i := 0; i < 3; i++ {
+		x.OTag("+a:solidFill")
+		x.OTag("a:schemeClr").Attr("val", "phClr").CTag()
+		x.CTag()
+	}
+	x.CTag() // fillStyleLst
+	x.OTag("+a:lnStyleLst")
+	for _, lw := range []int{6350, 12700, 19050} {
+		x.OTag("+a:ln").Attr("w", lw).Attr("cap", "flat").Attr("cmpd", "sng").Attr("algn", "ctr")
+		x.OTag("a:solidFill")
+		x.OTag("a:schemeClr").Attr("val", "phClr").CTag()
+		x.CTag()
+		x.OTag("a:prstDash").Attr("val", "solid").CTag()
+		x.OTag("a:miter").Attr("lim", 800000).CTag()
+		x.CTag()
+	}
+	x.CTag() // lnStyleLst
+	x.OTag("+a:effectStyleLst")
+	for i := 0; i < 3; i++ {
+		x.OTag("+a:effectStyle")
+		x.OTag("a:effectLst").CTag()
+		x.CTag()
+	}
+	x.CTag() // effectStyleLst
+	x.OTag("+a:bgFillStyleLst")
+	for i := 0; i < 3; i++ {
+		x.OTag("+a:solidFill")
+		x.OTag("a:schemeClr").Attr("val", "phClr").CTag()
+		x.CTag()
+	}
+	x.CTag() // bgFillStyleLst
+	x.CTag() // fmtScheme
+
+	x.CTag() // themeElements
+
+	x.OTag("+a:objectDefaults").CTag()
+	x.OTag("+a:extraClrSchemeLst").CTag()
+
+	x.CTag() // theme
+
+	return w.out.WriteBlob(abspath, bb.Bytes())
+}
+
+func (w *Writer) writeVBAProject(blob []byte) error {
+	_, rid := w.nextWorkbookID()
+
+	relpath := "vbaProject.bin"
+	abspath := "/xl/" + relpath
+
+	w.DefaultContentTypes["bin"] = "application/vnd.ms-office.vbaProject"
+	w.WorkbookRels[rid] = RelInfo{
+		Type:   "http://schemas.microsoft.com/office/2006/relationships/vbaProject",
+		Target: relpath,
+	}
+
+	return w.out.WriteBlob(abspath, blob)
+}
+
 func (w *Writer) writeWorkbook(wb *Workbook) error {
 	_, rid := w.nextGlobalID()
 
 	relpath := "xl/workbook.xml"
 	abspath := "/" + relpath
 
-	w.PartContentTypes[abspath] = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"
+	if len(wb.vbaProject) > 0 {
+		w.PartContentTypes[abspath] = "application/vnd.ms-excel.sheet.macroEnabled.main+xml"
+	} else {
+		w.PartContentTypes[abspath] = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"
+	}
 	w.GlobalRels[rid] = RelInfo{
 		Type:   "http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument",
 		Target: relpath,
 	}
 
 	bb := bytes.Buffer{}
-	x := xml.NewWriter(&bb, xml.WriterConfig{Indent: xml.Indent2Spaces})
-	x.XmlStandaloneDecl()
+	x := w.newXML(&bb, abspath)
 
 	x.OTag("workbook")
 	x.Attr("xmlns", "http://schemas.openxmlformats.org/spreadsheetml/2006/main")
@@ -347,77 +930,495 @@ func (w *Writer) writeWorkbook(wb *Workbook) error {
 			x.Attr("appName", wb.AppName)
 			x.CTag()
 		}
+	*/
 
+	if wb.Settings != (WorkbookSettings{}) || wb.Date1904 {
+		err := wb.Settings.validate()
+		if err != nil {
+			return err
+		}
 		x.OTag("+workbookPr")
-		x.Attr("showObjects", "all")
-		x.Attr("date1904", "false")
+		if wb.Date1904 {
+			x.Attr("date1904", 1)
+		}
+		x.OptStringAttr("showObjects", wb.Settings.ShowObjects)
+		if wb.Settings.BackupFile {
+			x.Attr("backupFile", 1)
+		}
+		x.OptStringAttr("codeName", w.sanitizeText(wb.Settings.CodeName))
 		x.CTag()
+	}
 
+	/*
 		x.OTag("+<workbookProtection")
 		x.CTag()
-
-		x.OTag("+bookViews")
-		{
-			x.OTag("+workbookView")
-			x.Attr("showHorizontalScroll", "true")
-			x.Attr("showVerticalScroll", "true")
-			x.Attr("showSheetTabs", "true")
-			x.Attr("tabRatio", "204")
-			x.Attr("windowHeight", "8192")
-			x.Attr("windowWidth", "16384")
-			x.Attr("xWindow", "0")
-			x.Attr("yWindow", "0")
-			x.CTag()
-		}
-		x.CTag()
 	*/
 
+	if err := w.writeBookViews(x, wb); err != nil {
+		return err
+	}
+
 	x.OTag("+sheets")
-	for _, sheet := range wb.Sheets {
+	for i, sheet := range wb.Sheets {
 		sheet_id, sheet_rid := w.nextWorkbookID()
 		{
 			x.OTag("+sheet")
 			x.Attr("name", sheet.Name)
 			x.Attr("sheetId", sheet_id)
+			x.OptStringAttr("state", sheet.Visibility.state())
 			x.Attr("r:id", sheet_rid)
 			x.CTag()
 		}
 
-		err := w.writeSheet(sheet, sheet_rid)
+		err := w.writeSheet(sheet, i+1, sheet_rid)
 		if err != nil {
 			return err
 		}
 	}
 	x.CTag()
 
-	/*
-
-		x.OTag("+definedNames")
-		x.CTag()
+	err := w.writeDefinedNames(x, wb)
+	if err != nil {
+		return err
+	}
 
+	if wb.CalcProperties != (CalcProperties{}) {
 		x.OTag("+calcPr")
-		x.Attr("iterateCount", "100")
-		x.Attr("refMode", "A1")
-		x.Attr("iterateDelta", "0.001")
+		switch wb.CalcProperties.Recalc {
+		case RecalcOnLoad:
+			x.Attr("fullCalcOnLoad", 1)
+		case RecalcOnSave:
+			x.Attr("calcOnSave", 1)
+		case RecalcManual:
+			x.Attr("calcMode", "manual").Attr("calcOnSave", 0)
+		}
+		if wb.CalcProperties.ForceFullCalc {
+			x.Attr("forceFullCalc", 1)
+		}
 		x.CTag()
-	*/
+	}
 
 	x.CTag()
 
 	return w.out.WriteBlob(abspath, bb.Bytes())
 }
 
+// writeDefinedNames writes the user defined names together with the
+// built-in names derived from sheet settings (print areas) as a single
+// definedNames element.
+func (w *Writer) writeDefinedNames(x *xml.Writer, wb *Workbook) error {
+	names := slices.Clone(wb.definedNames)
+	for _, sh := range wb.Sheets {
+		names = append(names, sh.sheetDefinedNames()...)
+	}
+	if len(names) == 0 {
+		return nil
+	}
+
+	x.OTag("+definedNames")
+	for _, dn := range names {
+		x.OTag("+definedName").Attr("name", w.sanitizeText(dn.Name))
+		if dn.Sheet != nil {
+			// localSheetId is the 0-based position of the sheet, not its sheetId
+			i := slices.Index(wb.Sheets, dn.Sheet)
+			if i < 0 {
+				return fmt.Errorf("defined name '%s' refers to a sheet that is not in the workbook", dn.Name)
+			}
+			x.Attr("localSheetId", i)
+		}
+		if dn.Hidden {
+			x.Attr("hidden", 1)
+		}
+		x.String(w.sanitizeText(dn.RefersTo))
+		x.CTag()
+	}
+	x.CTag()
+	return nil
+}
+
+// dxfID returns the index of a differential format in the dxfs section,
+// registering it if needed.
+func (w *Writer) dxfID(f *DiffFormat) int {
+	if i := slices.IndexFunc(w.dxfs, func(v *DiffFormat) bool { return *v == *f }); i >= 0 {
+		return i
+	}
+	// the number format gets its id now, the numFmts section is written
+	// before the dxfs
+	w.numFmtID(f.NumFmt)
+	v := *f
+	w.dxfs = append(w.dxfs, &v)
+	return len(w.dxfs) - 1
+}
+
+// writeDxf writes a differential format, which only has the elements that
+// it changes. Colors are validated when the rule is added.
+func (w *Writer) writeDxf(x *xml.Writer, f *DiffFormat) {
+	x.OTag("+dxf")
+	if f.Bold || f.Italic || f.FontColor != "" {
+		x.OTag("+font")
+		if f.Bold {
+			x.OTag("b").CTag()
+		}
+		if f.Italic {
+			x.OTag("i").CTag()
+		}
+		if f.FontColor != "" {
+			x.OTag("color").Attr("rgb", "FF"+f.FontColor).CTag()
+		}
+		x.CTag() // font
+	}
+	if f.NumFmt != "" {
+		x.OTag("+numFmt").Attr("numFmtId", w.numFmtID(f.NumFmt)).Attr("formatCode", w.sanitizeText(f.NumFmt)).CTag()
+	}
+	if f.FillColor != "" {
+		// the solid fill of a differential format takes the background
+		// color, unlike that of a cell format
+		x.OTag("+fill")
+		x.OTag("patternFill")
+		x.OTag("bgColor").Attr("rgb", "FF"+f.FillColor).CTag()
+		x.CTag() // patternFill
+		x.CTag() // fill
+	}
+	x.CTag() // dxf
+}
+
+func (w *Writer) FindFont(f *Font) int {
+	for i, v := range w.fonts {
+		if *v == *f {
+			return i
+		}
+	}
+	return -1
+}
+
+// fontID returns the index of a font in the fonts section, registering it
+// if needed. The first entry is always the default font.
+func (w *Writer) fontID(f *Font) int {
+	if len(w.fonts) == 0 {
+		w.fonts = append(w.fonts, &Font{})
+	}
+	v := *f
+	v.Color = canonicalColor(v.Color)
+	if v.VertAlign == VertAlignBaseline {
+		v.VertAlign = ""
+	}
+	i := w.FindFont(&v)
+	if i < 0 {
+		i = len(w.fonts)
+		w.fonts = append(w.fonts, &v)
+	}
+	return i
+}
+
+// canonicalColor normalizes a color so that equal colors written in
+// different ways share a style entry. Invalid colors are kept as is, they
+// are reported when the entry is written.
+func canonicalColor(s string) string {
+	if v, err := normalizeARGB(s); err == nil {
+		return v
+	}
+	return s
+}
+
+func (a *Alignment) validate() error {
+	if a.WrapText && a.ShrinkToFit {
+		return errors.New("wrap text and shrink to fit are mutually exclusive")
+	}
+	if a.Indent < 0 || a.Indent > MaxIndent {
+		return fmt.Errorf("indent %d is out of the range 0-%d", a.Indent, MaxIndent)
+	}
+	if (a.TextRotation < 0 || a.TextRotation > 180) && a.TextRotation != TextRotationStacked {
+		return fmt.Errorf("invalid text rotation %d", a.TextRotation)
+	}
+	return nil
+}
+
+func writeAlignment(x *xml.Writer, a *Alignment) error {
+	if err := a.validate(); err != nil {
+		return err
+	}
+	x.OTag("alignment")
+	x.OptStringAttr("horizontal", a.Horizontal)
+	x.OptStringAttr("vertical", a.Vertical)
+	if a.TextRotation != 0 {
+		x.Attr("textRotation", a.TextRotation)
+	}
+	if a.WrapText {
+		x.Attr("wrapText", 1)
+	}
+	if a.Indent > 0 {
+		x.Attr("indent", a.Indent)
+	}
+	if a.ShrinkToFit {
+		x.Attr("shrinkToFit", 1)
+	}
+	x.CTag()
+	return nil
+}
+
+func writeFont(x *xml.Writer, f *Font) error {
+	x.OTag("+font")
+	if f.Bold {
+		x.OTag("b").CTag()
+	}
+	if f.Italic {
+		x.OTag("i").CTag()
+	}
+	switch f.VertAlign {
+	case "":
+	case VertAlignSuperscript, VertAlignSubscript:
+		x.OTag("vertAlign").Attr("val", f.VertAlign).CTag()
+	default:
+		return fmt.Errorf("invalid font vertical alignment '%s'", f.VertAlign)
+	}
+	if f.Size > 0 {
+		x.OTag("sz").Attr("val", f.Size).CTag()
+	} else {
+		x.OTag("sz").Attr("val", 11).CTag()
+	}
+	if f.Color != "" {
+		argb, err := normalizeARGB(f.Color)
+		if err != nil {
+			return err
+		}
+		x.OTag("color").Attr("rgb", argb).CTag()
+	}
+	if f.Name == "" {
+		x.OTag("name").Attr("val", "Calibri").CTag()
+		x.OTag("family").Attr("val", 2).CTag()
+	} else {
+		x.OTag("name").Attr("val", cleanText(f.Name, false, false)).CTag()
+		if f.Family != 0 {
+			x.OTag("family").Attr("val", f.Family).CTag()
+		}
+	}
+	x.CTag() // font
+	return nil
+}
+
+func (w *Writer) FindFill(f *Fill) int {
+	for i, v := range w.fills {
+		if *v == *f {
+			return i
+		}
+	}
+	return -1
+}
+
+// fillID returns the index of a fill in the fills section, registering it
+// if needed. Excel expects the first two entries to be the "none" and
+// "gray125" pattern fills, whatever the cells use.
+func (w *Writer) fillID(f *Fill) int {
+	if len(w.fills) == 0 {
+		w.fills = append(w.fills, &Fill{PatternType: "none"}, &Fill{PatternType: "gray125"})
+	}
+	if f.Empty() {
+		return 0
+	}
+	v := *f
+	v.FgColor = canonicalColor(v.FgColor)
+	v.BgColor = canonicalColor(v.BgColor)
+	i := w.FindFill(&v)
+	if i < 0 {
+		i = len(w.fills)
+		w.fills = append(w.fills, &v)
+	}
+	return i
+}
+
+func writeFill(x *xml.Writer, f *Fill) error {
+	pattern := f.PatternType
+	if pattern == "" {
+		pattern = "solid"
+	}
+	if !slices.Contains(patternTypes, pattern) {
+		return fmt.Errorf("invalid fill pattern '%s'", pattern)
+	}
+	x.OTag("+fill")
+	x.OTag("patternFill").Attr("patternType", pattern)
+	for _, c := range []struct {
+		tag   xml.NameString
+		color string
+	}{{"fgColor", f.FgColor}, {"bgColor", f.BgColor}} {
+		if c.color == "" {
+			continue
+		}
+		argb, err := normalizeARGB(c.color)
+		if err != nil {
+			return err
+		}
+		x.OTag(c.tag).Attr("rgb", argb).CTag()
+	}
+	x.CTag() // patternFill
+	x.CTag() // fill
+	return nil
+}
+
+func (w *Writer) FindBorder(b *Border) int {
+	for i, v := range w.borders {
+		if *v == *b {
+			return i
+		}
+	}
+	return -1
+}
+
+// borderID returns the index of a border in the borders section,
+// registering it if needed. The first entry is always the empty border.
+func (w *Writer) borderID(b *Border) int {
+	if len(w.borders) == 0 {
+		w.borders = append(w.borders, &Border{})
+	}
+	v := *b
+	for _, e := range v.edges() {
+		e.edge.Color = canonicalColor(e.edge.Color)
+	}
+	i := w.FindBorder(&v)
+	if i < 0 {
+		i = len(w.borders)
+		w.borders = append(w.borders, &v)
+	}
+	return i
+}
+
+// edges lists the edges of the border in schema order.
+func (b *Border) edges() []struct {
+	tag  xml.NameString
+	edge *BorderEdge
+} {
+	return []struct {
+		tag  xml.NameString
+		edge *BorderEdge
+	}{
+		{"left", &b.Left},
+		{"right", &b.Right},
+		{"top", &b.Top},
+		{"bottom", &b.Bottom},
+		{"diagonal", &b.Diagonal},
+	}
+}
+
+// writeBorder writes a border, edges without a style are omitted.
+func writeBorder(x *xml.Writer, b *Border) error {
+	x.OTag("+border")
+	if b.DiagonalUp {
+		x.Attr("diagonalUp", 1)
+	}
+	if b.DiagonalDown {
+		x.Attr("diagonalDown", 1)
+	}
+	for _, e := range b.edges() {
+		if e.edge.Style == "" {
+			continue
+		}
+		if !slices.Contains(borderStyles, e.edge.Style) {
+			return fmt.Errorf("invalid border style '%s'", e.edge.Style)
+		}
+		x.OTag(e.tag).Attr("style", e.edge.Style)
+		if e.edge.Color != "" {
+			argb, err := normalizeARGB(e.edge.Color)
+			if err != nil {
+				return err
+			}
+			x.OTag("color").Attr("rgb", argb).CTag()
+		}
+		x.CTag()
+	}
+	x.CTag() // border
+	return nil
+}
+
+// FindXF returns the cellXfs index of a style registered so far, or -1.
 func (w *Writer) FindXF(xf *XF) int {
-	for i, v := range w.xfs {
-		if *v == *xf {
+	v := canonicalXF(xf)
+	for i, u := range w.xfs {
+		if *u == v {
 			return i
 		}
 	}
 	return -1
 }
 
-func (w *Writer) writeSheet(sh *Sheet, rid string) error {
-	relpath := "worksheets/" + sh.Name + ".xml"
+// canonicalXF normalizes the equivalent spellings of a style, so that they
+// share a cellXfs entry.
+func canonicalXF(xf *XF) XF {
+	v := *xf
+	if v.Font.VertAlign == VertAlignBaseline {
+		v.Font.VertAlign = ""
+	}
+	return v
+}
+
+// styleIndex returns the cellXfs index for xf, registering it if needed.
+// The first entry is always the default (empty) style.
+func (w *Writer) styleIndex(xf *XF) int {
+	if len(w.xfs) == 0 {
+		w.xfs = append(w.xfs, &XF{})
+	}
+	i := w.FindXF(xf)
+	if i < 0 {
+		i = len(w.xfs)
+		v := canonicalXF(xf)
+		w.xfs = append(w.xfs, &v)
+	}
+	return i
+}
+
+// cellXF resolves the effective style of a cell: the cell's own style
+// takes precedence, followed by the column style and then the sheet
+// default. A number format that is missing from the resolved style is
+// inherited from the column style, then from the sheet default.
+func cellXF(sh *Sheet, c *Cell) XF {
+	col := sh.Columns[c.columnNumber]
+	xf := c.XF
+	if xf.Empty() {
+		if col != nil && !col.Style.Empty() {
+			xf = col.Style
+		} else {
+			xf = sh.DefaultStyle
+		}
+	}
+	// unknown style names are reported by writeSheet
+	if rxf, err := sh.workbook.resolveStyle(xf); err == nil {
+		xf = rxf
+	}
+	if xf.NumFmt == "" && col != nil {
+		xf.NumFmt = col.Style.NumFmt
+	}
+	if xf.NumFmt == "" {
+		xf.NumFmt = sh.DefaultStyle.NumFmt
+	}
+	if xf.NumFmt == "" && c.typ == CellTypeDate {
+		xf.NumFmt = DateFormat
+		if c.dateTime {
+			xf.NumFmt = DateTimeFormat
+		}
+	}
+	return xf
+}
+
+// numFmtID returns the id of a number format code: built-in formats use
+// their reserved ids, custom formats are allocated ids starting at 164.
+func (w *Writer) numFmtID(code string) int {
+	if code == "" {
+		return 0
+	}
+	if id, ok := builtinNumFmts[code]; ok {
+		return id
+	}
+	if i := slices.Index(w.numFmts, code); i >= 0 {
+		return 164 + i
+	}
+	w.numFmts = append(w.numFmts, code)
+	return 164 + len(w.numFmts) - 1
+}
+
+// writeSheet writes the n-th worksheet part. Parts are named by position
+// rather than by sheet name, which may contain characters that are not
+// valid in part names.
+func (w *Writer) writeSheet(sh *Sheet, n int, rid string) error {
+	name := fmt.Sprintf("sheet%d.xml", n)
+	relpath := "worksheets/" + name
 	abspath := "/xl/" + relpath
 
 	w.PartContentTypes[abspath] = "application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"
@@ -427,23 +1428,69 @@ func (w *Writer) writeSheet(sh *Sheet, rid string) error {
 	}
 
 	bb := bytes.Buffer{}
-	x := xml.NewWriter(&bb, xml.WriterConfig{Indent: xml.Indent2Spaces})
-	x.XmlStandaloneDecl()
+	x := w.newXML(&bb, abspath)
+
+	rels := w.collectSheetRels(sh)
 
 	x.OTag("worksheet")
 	x.Attr("xmlns", "http://schemas.openxmlformats.org/spreadsheetml/2006/main")
-	x.Attr("xmlns:r", "http://schemas.openxmlformats.org/officeDocument/2006/relationships")
+	if len(rels.rels) > 0 {
+		x.Attr("xmlns:r", "http://schemas.openxmlformats.org/officeDocument/2006/relationships")
+	}
+
+	err := w.writeSheetPr(x, sh)
+	if err != nil {
+		return err
+	}
+	w.writeSheetViews(x, sh)
+
+	rowLevel, colLevel := sh.maxRowOutlineLevel(), sh.maxColOutlineLevel()
+	if sh.DefaultRowHeight > 0 || sh.DefaultColWidth > 0 || rowLevel > 0 || colLevel > 0 {
+		x.OTag("+sheetFormatPr")
+		if sh.DefaultColWidth > 0 {
+			x.Attr("defaultColWidth", sh.DefaultColWidth)
+		}
+		// defaultRowHeight is required, 15 is the height of the default font
+		if sh.DefaultRowHeight > 0 {
+			x.Attr("defaultRowHeight", sh.DefaultRowHeight).Attr("customHeight", 1)
+		} else {
+			x.Attr("defaultRowHeight", 15)
+		}
+		if rowLevel > 0 {
+			x.Attr("outlineLevelRow", rowLevel)
+		}
+		if colLevel > 0 {
+			x.Attr("outlineLevelCol", colLevel)
+		}
+		x.CTag()
+	}
 
 	if len(sh.Columns) > 0 {
 		x.OTag("+cols")
-		enumerate(sh.Columns, func(n int, v *Column) error {
+		err = enumerate(sh.Columns, func(n int, v *Column) error {
 			x.OTag("+col").Attr("min", n).Attr("max", n)
 			if v.Width > 0 {
 				x.Attr("width", v.Width).Attr("customWidth", 1)
 			}
+			if !v.Style.Empty() && !sh.RawData {
+				xf, err := sh.workbook.resolveStyle(v.Style)
+				if err != nil {
+					return fmt.Errorf("sheet '%s', column %d: %w", sh.Name, n, err)
+				}
+				x.Attr("style", w.styleIndex(&xf))
+			}
+			if v.Hidden {
+				x.Attr("hidden", 1)
+			}
+			if v.OutlineLevel > 0 {
+				x.Attr("outlineLevel", v.OutlineLevel)
+			}
 			x.CTag()
 			return nil
 		})
+		if err != nil {
+			return err
+		}
 		x.CTag()
 	}
 
@@ -453,16 +1500,44 @@ func (w *Writer) writeSheet(sh *Sheet, rid string) error {
 		if row.Height > 0 {
 			x.Attr("ht", row.Height).Attr("customHeight", 1)
 		}
+		if row.Hidden {
+			x.Attr("hidden", 1)
+		}
+		if row.OutlineLevel > 0 {
+			x.Attr("outlineLevel", row.OutlineLevel)
+		}
+		if row.Collapsed {
+			x.Attr("collapsed", 1)
+		}
 
 		for _, cell := range row.Cells {
-			x.OTag("+c").Attr("r", cell.coord)
-
-			if !cell.XF.Empty() {
-				i := w.FindXF(&cell.XF)
-				if i < 0 {
-					w.xfs = append(w.xfs, &cell.XF)
+			s := 0
+			if !sh.RawData {
+				xf := cellXF(sh, cell)
+				if _, err := sh.workbook.resolveStyle(xf); err != nil {
+					return fmt.Errorf("sheet '%s', cell %s: %w", sh.Name, cell.coord, err)
+				}
+				if !xf.Empty() {
+					s = w.styleIndex(&xf)
 				}
-				x.Attr("s", i)
+			}
+
+			vm, err := w.cellValueMetadata(cell)
+			if err != nil {
+				return err
+			}
+
+			if cell.typ == CellTypeUnset && s == 0 && vm == 0 {
+				// nothing to write for a blank cell without formatting
+				continue
+			}
+
+			x.OTag("+c").Attr("r", cell.coord)
+			if s > 0 {
+				x.Attr("s", s)
+			}
+			if vm > 0 {
+				x.Attr("vm", vm)
 			}
 
 			switch cell.typ {
@@ -472,45 +1547,41 @@ func (w *Writer) writeSheet(sh *Sheet, rid string) error {
 			case CellTypeNumber:
 				x.Attr("t", "n")
 				x.OTag("v").Write(cell.v).CTag()
+			case CellTypeDate:
+				v := cell.v
+				if sh.workbook.Date1904 {
+					v, err = date1904Serial(v)
+					if err != nil {
+						return fmt.Errorf("sheet '%s', cell %s: %w", sh.Name, cell.coord, err)
+					}
+				}
+				x.Attr("t", "n")
+				x.OTag("v").Write(v).CTag()
 			case CellTypeError:
+				if cell.nonFinite && w.RejectNonFinite {
+					return fmt.Errorf("sheet '%s', cell %s: non-finite number", sh.Name, cell.coord)
+				}
 				x.Attr("t", "e")
 				x.OTag("v").Write(cell.v).CTag()
-			case CellTypeSharedString:
-				x.Attr("t", "s")
-				x.OTag("v").Write(w.SharedString(cell.v)).CTag()
-			case cellTypePicture:
-				if cell.picture == nil {
-					return errors.New("missing picture data")
-				}
-				ext := strings.ToLower(cell.picture.Extension)
-				if ext == ".jpg" {
-					ext = ".jpeg"
-				}
-				if ext == ".jpeg" {
-					w.DefaultContentTypes["jpeg"] = "image/jpeg"
-				} else if ext == ".png" {
-					w.DefaultContentTypes["png"] = "image/png"
+			case CellTypeSharedString, CellTypeInlineString:
+				if cell.typ == CellTypeInlineString || w.InlineStrings {
+					x.Attr("t", "inlineStr")
+					x.OTag("is")
+					w.writeText(x, cell.v)
+					x.CTag() // is
 				} else {
-					return fmt.Errorf("unsupported image extension %s", ext)
-				}
-				n := fmt.Sprintf("%.16x%s", BlobHash(cell.picture.Blob), ext)
-				info, ok := w.mediaMap[n]
-				if !ok {
-					_, rid := w.nextRichDataID()
-					info = &MediaInfo{
-						Name: n,
-						Blob: cell.picture.Blob,
-						IId:  len(w.media),
-						RId:  rid,
-					}
-					w.mediaMap[n] = info
-					w.media = append(w.media, info)
+					x.Attr("t", "s")
+					x.OTag("v").Write(w.SharedString(cell.v)).CTag()
+					w.sharedStringRefs++
 				}
-				if len(info.Blob) == 0 {
-					return errors.New("empty picture data")
-				}
-
-				x.Attr("t", "e").Attr("vm", info.IId+1)
+			case cellTypeSharedIndex:
+				// checked by checkSharedIndices
+				x.Attr("t", "s")
+				x.OTag("v").Write(cell.v).CTag()
+				w.sharedStringRefs++
+			case cellTypePicture:
+				// the actual value comes from the rich value metadata
+				x.Attr("t", "e")
 				x.OTag("v").Write("#VALUE!").CTag()
 			}
 			x.CTag() // c
@@ -520,11 +1591,724 @@ func (w *Writer) writeSheet(sh *Sheet, rid string) error {
 	}
 	x.CTag() // sheetData
 
+	if sh.protection != nil {
+		w.writeSheetProtection(x, sh.protection)
+	}
+
+	if len(sh.merges) > 0 {
+		x.OTag("+mergeCells").Attr("count", len(sh.merges))
+		for _, m := range sh.merges {
+			x.OTag("+mergeCell").Attr("ref", m.String()).CTag()
+		}
+		x.CTag()
+	}
+
+	w.writeConditionalFormats(x, sh)
+
+	if len(sh.dataValidations) > 0 {
+		x.OTag("+dataValidations").Attr("count", len(sh.dataValidations))
+		for _, dv := range sh.dataValidations {
+			x.OTag("+dataValidation").Attr("type", dv.Type)
+			if dv.Operator != "" && dv.Operator != "between" {
+				x.Attr("operator", dv.Operator)
+			}
+			if dv.AllowBlank {
+				x.Attr("allowBlank", 1)
+			}
+			if dv.HideDropDown {
+				x.Attr("showDropDown", 1)
+			}
+			x.Attr("showInputMessage", 1).Attr("showErrorMessage", 1)
+			x.OptStringAttr("errorTitle", w.sanitizeText(dv.ErrorTitle))
+			x.OptStringAttr("error", w.sanitizeText(dv.Error))
+			x.OptStringAttr("promptTitle", w.sanitizeText(dv.PromptTitle))
+			x.OptStringAttr("prompt", w.sanitizeText(dv.Prompt))
+			x.Attr("sqref", dv.sqref.String())
+			x.OTag("formula1").String(w.sanitizeText(dv.Formula1)).CTag()
+			if dv.Formula2 != "" {
+				x.OTag("formula2").String(w.sanitizeText(dv.Formula2)).CTag()
+			}
+			x.CTag() // dataValidation
+		}
+		x.CTag() // dataValidations
+	}
+
+	if len(rels.hyperlinks) > 0 {
+		x.OTag("+hyperlinks")
+		for _, h := range rels.hyperlinks {
+			x.OTag("+hyperlink").Attr("ref", h.ref)
+			x.OptStringAttr("r:id", h.rid)
+			x.OptStringAttr("location", w.sanitizeText(h.link.Location))
+			x.OptStringAttr("tooltip", w.sanitizeText(h.link.Tooltip))
+			x.CTag()
+		}
+		x.CTag()
+	}
+
+	if ps := &sh.PageSetup; !ps.empty() {
+		x.OTag("+pageSetup")
+		if ps.PaperSize != 0 {
+			x.Attr("paperSize", int(ps.PaperSize))
+		}
+		if ps.Scale != 0 {
+			x.Attr("scale", ps.Scale)
+		}
+		if ps.fitToPage() {
+			x.Attr("fitToWidth", ps.FitToWidth).Attr("fitToHeight", ps.FitToHeight)
+		}
+		x.OptStringAttr("orientation", ps.Orientation)
+		x.CTag()
+	}
+
+	if hf := &sh.HeaderFooter; !hf.empty() {
+		x.OTag("+headerFooter")
+		if hf.differentOddEven() {
+			x.Attr("differentOddEven", 1)
+		}
+		if hf.differentFirst() {
+			x.Attr("differentFirst", 1)
+		}
+		for _, t := range []struct {
+			tag  xml.NameString
+			text string
+		}{
+			{"oddHeader", hf.OddHeader},
+			{"oddFooter", hf.OddFooter},
+			{"evenHeader", hf.EvenHeader},
+			{"evenFooter", hf.EvenFooter},
+			{"firstHeader", hf.FirstHeader},
+			{"firstFooter", hf.FirstFooter},
+		} {
+			if t.text != "" {
+				x.OTag("+" + t.tag).String(w.sanitizeText(t.text)).CTag()
+			}
+		}
+		x.CTag()
+	}
+
+	if rels.legacyDrawing != "" {
+		x.OTag("+legacyDrawing").Attr("r:id", rels.legacyDrawing).CTag()
+	}
+
+	w.writeSheetExtLst(x, sh)
+
 	x.CTag() // worksheet
 
+	if rels.commentsN > 0 {
+		err := w.writeComments(sh, rels.commentsN)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(rels.rels) > 0 {
+		err := w.writeRels("/xl/worksheets/_rels/"+name+".rels", rels.rels)
+		if err != nil {
+			return err
+		}
+	}
+
 	return w.out.WriteBlob(abspath, bb.Bytes())
 }
 
+// addValueMetadata registers a valueMetadata entry and returns the 1-based
+// index that cells refer to with the vm attribute.
+func (w *Writer) addValueMetadata(vm valueMetadata) int {
+	w.valueMetadata = append(w.valueMetadata, vm)
+	return len(w.valueMetadata)
+}
+
+// cellValueMetadata returns the 1-based valueMetadata index referenced by
+// the vm attribute of the cell, or 0 if the cell has none. Each value type
+// that carries metadata registers its entries here; pictures are the only
+// one so far, they get their media and rich value registered as well.
+func (w *Writer) cellValueMetadata(cell *Cell) (int, error) {
+	if cell.typ != cellTypePicture {
+		return 0, nil
+	}
+	ext, ctype, err := pictureType(cell.picture)
+	if err != nil {
+		return 0, err
+	}
+	w.DefaultContentTypes[ext[1:]] = ctype
+	n := fmt.Sprintf("%.16x%s", BlobHash(cell.picture.Blob), ext)
+	info, ok := w.mediaMap[n]
+	if !ok {
+		_, rid := w.nextRichDataID()
+		info = &MediaInfo{
+			Name: n,
+			Blob: cell.picture.Blob,
+			IId:  len(w.media),
+			RId:  rid,
+		}
+		w.mediaMap[n] = info
+		w.media = append(w.media, info)
+		info.VM = w.addValueMetadata(valueMetadata{richValue: info.IId})
+	}
+	return info.VM, nil
+}
+
+// writeSheetPr writes the sheetPr element, which collects the sheet-wide
+// properties; it is omitted when none are set.
+func (w *Writer) writeSheetPr(x *xml.Writer, sh *Sheet) error {
+	outline := sh.maxRowOutlineLevel() > 0 || sh.maxColOutlineLevel() > 0
+	fitToPage := sh.PageSetup.fitToPage()
+	if sh.CodeName == "" && sh.EnableFormatConditionsCalculation == nil && sh.TabColor == "" &&
+		!outline && !fitToPage {
+		return nil
+	}
+	x.OTag("+sheetPr")
+	x.OptStringAttr("codeName", w.sanitizeText(sh.CodeName))
+	if v := sh.EnableFormatConditionsCalculation; v != nil {
+		x.Attr("enableFormatConditionsCalculation", boolAttr(*v))
+	}
+	if sh.TabColor != "" {
+		argb, err := normalizeARGB(sh.TabColor)
+		if err != nil {
+			return fmt.Errorf("sheet '%s': tab color: %w", sh.Name, err)
+		}
+		x.OTag("+tabColor").Attr("rgb", argb).CTag()
+	}
+	if outline {
+		x.OTag("+outlinePr")
+		x.Attr("summaryBelow", boolAttr(!sh.SummaryRowsAbove))
+		x.Attr("summaryRight", boolAttr(!sh.SummaryColumnsLeft))
+		x.CTag()
+	}
+	if fitToPage {
+		// the fitToWidth and fitToHeight attributes of pageSetup are
+		// ignored unless this is set
+		x.OTag("+pageSetUpPr").Attr("fitToPage", 1).CTag()
+	}
+	x.CTag()
+	return nil
+}
+
+func boolAttr(v bool) int {
+	if v {
+		return 1
+	}
+	return 0
+}
+
+// writeSheetProtection writes the sheetProtection element. The attributes
+// are set when the action is protected, those that default to protected
+// are only written when the action is allowed.
+func (w *Writer) writeSheetProtection(x *xml.Writer, p *SheetProtection) {
+	x.OTag("+sheetProtection")
+	if h, _ := passwordHash(p.Password); h != 0 {
+		x.Attr("password", fmt.Sprintf("%04X", h))
+	}
+	x.Attr("sheet", 1)
+	if !p.EditObjects {
+		x.Attr("objects", 1)
+	}
+	if !p.EditScenarios {
+		x.Attr("scenarios", 1)
+	}
+	for _, a := range []struct {
+		name    xml.NameString
+		allowed bool
+	}{
+		{"formatCells", p.FormatCells},
+		{"formatColumns", p.FormatColumns},
+		{"formatRows", p.FormatRows},
+		{"insertColumns", p.InsertColumns},
+		{"insertRows", p.InsertRows},
+		{"insertHyperlinks", p.InsertHyperlinks},
+		{"deleteColumns", p.DeleteColumns},
+		{"deleteRows", p.DeleteRows},
+	} {
+		if a.allowed {
+			x.Attr(a.name, 0)
+		}
+	}
+	if !p.SelectLockedCells {
+		x.Attr("selectLockedCells", 1)
+	}
+	if p.Sort {
+		x.Attr("sort", 0)
+	}
+	if p.AutoFilter {
+		x.Attr("autoFilter", 0)
+	}
+	x.CTag()
+}
+
+// writeBookViews writes the bookViews element, which is omitted when the
+// workbook uses the default window settings.
+func (w *Writer) writeBookViews(x *xml.Writer, wb *Workbook) error {
+	if wb.ActiveSheet == nil && wb.WindowWidth == 0 && wb.WindowHeight == 0 {
+		return nil
+	}
+	if wb.WindowWidth < 0 || wb.WindowHeight < 0 {
+		return fmt.Errorf("invalid window size %dx%d", wb.WindowWidth, wb.WindowHeight)
+	}
+	activeTab := 0
+	if wb.ActiveSheet != nil {
+		activeTab = slices.Index(wb.Sheets, wb.ActiveSheet)
+		if activeTab < 0 {
+			return fmt.Errorf("active sheet '%s' is not part of the workbook", wb.ActiveSheet.Name)
+		}
+		if wb.ActiveSheet.Visibility != VisibilityVisible {
+			return fmt.Errorf("active sheet '%s' is hidden", wb.ActiveSheet.Name)
+		}
+	}
+
+	x.OTag("+bookViews")
+	x.OTag("+workbookView")
+	if wb.WindowWidth > 0 {
+		x.Attr("windowWidth", wb.WindowWidth)
+	}
+	if wb.WindowHeight > 0 {
+		x.Attr("windowHeight", wb.WindowHeight)
+	}
+	if activeTab > 0 {
+		x.Attr("activeTab", activeTab)
+	}
+	x.CTag()
+	x.CTag()
+	return nil
+}
+
+// writeSheetViews writes the sheetViews element, which is omitted when the
+// sheet uses the default view settings.
+func (w *Writer) writeSheetViews(x *xml.Writer, sh *Sheet) {
+	if sh.topLeftCell == "" && sh.activeCell == "" && sh.pane == nil &&
+		sh.ZoomScale == 0 && (sh.ViewType == "" || sh.ViewType == ViewNormal) && sh.ShowGridlines == nil && sh.ShowRowColHeaders == nil && !sh.RightToLeft {
+		return
+	}
+	x.OTag("+sheetViews")
+	x.OTag("+sheetView")
+	if sh.ShowGridlines != nil {
+		x.Attr("showGridLines", boolAttr(*sh.ShowGridlines))
+	}
+	if sh.ShowRowColHeaders != nil {
+		x.Attr("showRowColHeaders", boolAttr(*sh.ShowRowColHeaders))
+	}
+	if sh.RightToLeft {
+		x.Attr("rightToLeft", 1)
+	}
+	x.OptStringAttr("topLeftCell", sh.topLeftCell)
+	if sh.ZoomScale != 0 {
+		x.Attr("zoomScale", sh.ZoomScale)
+	}
+	if sh.ViewType != "" && sh.ViewType != ViewNormal {
+		x.Attr("view", sh.ViewType)
+	}
+	x.Attr("workbookViewId", 0)
+	p := sh.pane
+	if p != nil {
+		x.OTag("+pane")
+		if p.xSplit > 0 {
+			x.Attr("xSplit", p.xSplit)
+		}
+		if p.ySplit > 0 {
+			x.Attr("ySplit", p.ySplit)
+		}
+		if p.frozen {
+			x.Attr("topLeftCell", p.topLeftCell())
+		}
+		x.Attr("activePane", p.activePane())
+		if p.frozen {
+			x.Attr("state", "frozen")
+		}
+		x.CTag()
+	}
+	// each pane has its own selection, the active cell belongs to the
+	// active pane, which is listed last
+	if p != nil {
+		panes := p.selectionPanes()
+		for i, name := range panes {
+			x.OTag("+selection").Attr("pane", name)
+			if i == len(panes)-1 && sh.activeCell != "" {
+				x.Attr("activeCell", sh.activeCell).Attr("sqref", sh.activeCell)
+			}
+			x.CTag()
+		}
+	} else if sh.activeCell != "" {
+		x.OTag("+selection").Attr("activeCell", sh.activeCell).Attr("sqref", sh.activeCell).CTag()
+	}
+	x.CTag() // sheetView
+	x.CTag() // sheetViews
+}
+
+// writeConditionalFormats writes the classic conditionalFormatting
+// elements, one per rule.
+func (w *Writer) writeConditionalFormats(x *xml.Writer, sh *Sheet) {
+	for _, cf := range sh.condFormats {
+		if !cf.classic() {
+			continue
+		}
+		r := &cf.rule
+		x.OTag("+conditionalFormatting").Attr("sqref", cf.sqref.String())
+		x.OTag("+cfRule")
+		switch r.Type {
+		case CondFormatDataBar:
+			x.Attr("type", "dataBar")
+		case CondFormatIconSet:
+			x.Attr("type", "iconSet")
+		case CondFormatCellIs:
+			x.Attr("type", "cellIs").Attr("dxfId", w.dxfID(&r.Format))
+		case CondFormatColorScale:
+			x.Attr("type", "colorScale")
+		}
+		x.Attr("priority", cf.priority)
+		if r.StopIfTrue {
+			x.Attr("stopIfTrue", 1)
+		}
+		if r.Type == CondFormatCellIs {
+			x.Attr("operator", r.Operator)
+		}
+
+		switch r.Type {
+		case CondFormatDataBar:
+			x.OTag("+dataBar")
+			x.OTag("+cfvo").Attr("type", "min").CTag()
+			x.OTag("+cfvo").Attr("type", "max").CTag()
+			x.OTag("+color").Attr("rgb", "FF"+r.BarColor).CTag()
+			x.CTag() // dataBar
+		case CondFormatIconSet:
+			x.OTag("+iconSet").Attr("iconSet", r.IconSet)
+			if r.ReverseIcons {
+				x.Attr("reverse", 1)
+			}
+			for _, t := range r.iconThresholds() {
+				x.OTag("+cfvo").Attr("type", "percent").Attr("val", t).CTag()
+			}
+			x.CTag() // iconSet
+		case CondFormatCellIs:
+			x.OTag("+formula").String(w.sanitizeText(r.Formula)).CTag()
+			if r.Formula2 != "" {
+				x.OTag("+formula").String(w.sanitizeText(r.Formula2)).CTag()
+			}
+		case CondFormatColorScale:
+			x.OTag("+colorScale")
+			x.OTag("+cfvo").Attr("type", "min").CTag()
+			if r.MidColor != "" {
+				x.OTag("+cfvo").Attr("type", "percentile").Attr("val", 50).CTag()
+			}
+			x.OTag("+cfvo").Attr("type", "max").CTag()
+			for _, c := range []string{r.MinColor, r.MidColor, r.MaxColor} {
+				if c != "" {
+					x.OTag("+color").Attr("rgb", "FF"+c).CTag()
+				}
+			}
+			x.CTag() // colorScale
+		}
+
+		if r.Extended {
+			x.OTag("+extLst")
+			x.OTag("+ext").Attr("uri", "{B025F937-C7B1-47D3-B67F-A62EFF666E3E}")
+			x.Attr("xmlns:x14", "http://schemas.microsoft.com/office/spreadsheetml/2009/9/main")
+			x.OTag("x14:id").String(cf.extID()).CTag()
+			x.CTag() // ext
+			x.CTag() // extLst
+		}
+		x.CTag() // cfRule
+		x.CTag() // conditionalFormatting
+	}
+}
+
+// writeSheetExtLst writes the extension list of the worksheet, which holds
+// the x14 form of extended conditional formatting rules.
+func (w *Writer) writeSheetExtLst(x *xml.Writer, sh *Sheet) {
+	if !slices.ContainsFunc(sh.condFormats, func(cf *conditionalFormat) bool {
+		return cf.rule.Extended
+	}) {
+		return
+	}
+
+	x.OTag("+extLst")
+	x.OTag("+ext").Attr("uri", "{78C0D931-6437-407d-A8EE-F0AAD7539E65}")
+	x.Attr("xmlns:x14", "http://schemas.microsoft.com/office/spreadsheetml/2009/9/main")
+	x.OTag("+x14:conditionalFormattings")
+	for _, cf := range sh.condFormats {
+		r := &cf.rule
+		if !r.Extended {
+			continue
+		}
+		x.OTag("+x14:conditionalFormatting")
+		x.Attr("xmlns:xm", "http://schemas.microsoft.com/office/excel/2006/main")
+		x.OTag("+x14:cfRule")
+		switch r.Type {
+		case CondFormatDataBar:
+			// the priority is carried by the linked classic rule
+			x.Attr("type", "dataBar").Attr("id", cf.extID())
+			x.OTag("+x14:dataBar")
+			x.Attr("minLength", 0).Attr("maxLength", 100)
+			if r.SolidFill {
+				x.Attr("gradient", 0)
+			}
+			x.OTag("+x14:cfvo").Attr("type", "autoMin").CTag()
+			x.OTag("+x14:cfvo").Attr("type", "autoMax").CTag()
+			x.OTag("+x14:negativeFillColor").Attr("rgb", "FF"+r.NegativeColor).CTag()
+			x.OTag("+x14:axisColor").Attr("rgb", "FF000000").CTag()
+			x.CTag() // x14:dataBar
+
+		case CondFormatIconSet:
+			x.Attr("type", "iconSet").Attr("priority", cf.priority).Attr("id", cf.extID())
+			if r.StopIfTrue {
+				x.Attr("stopIfTrue", 1)
+			}
+			x.OTag("+x14:iconSet").Attr("iconSet", r.IconSet)
+			if r.ReverseIcons {
+				x.Attr("reverse", 1)
+			}
+			if len(r.Icons) > 0 {
+				x.Attr("custom", 1)
+			}
+			for _, t := range r.iconThresholds() {
+				x.OTag("+x14:cfvo").Attr("type", "percent")
+				x.OTag("xm:f").Write(t).CTag()
+				x.CTag() // x14:cfvo
+			}
+			for _, icon := range r.Icons {
+				x.OTag("+x14:cfIcon").Attr("iconSet", icon.IconSet).Attr("iconId", icon.Index).CTag()
+			}
+			x.CTag() // x14:iconSet
+		}
+		x.CTag() // x14:cfRule
+		x.OTag("+xm:sqref").String(cf.sqref.String()).CTag()
+		x.CTag() // x14:conditionalFormatting
+	}
+	x.CTag() // x14:conditionalFormattings
+	x.CTag() // ext
+	x.CTag() // extLst
+}
+
+// sheetRels holds the relationships of a single worksheet part.
+type sheetRels struct {
+	lastId int
+	rels   map[string]RelInfo
+
+	commentsN     int    // number of the comments and vml drawing parts, 0 if none
+	legacyDrawing string // rid of the vml drawing
+	hyperlinks    []sheetHyperlink
+}
+
+func (sr *sheetRels) add(info RelInfo) string {
+	sr.lastId++
+	rid := relID(sr.lastId)
+	sr.rels[rid] = info
+	return rid
+}
+
+// collectSheetRels is a pre-pass over the sheet that registers every
+// relationship the worksheet part references through r:id attributes
+// (hyperlinks, drawings, table parts). It runs before the root element
+// is written, so that the r namespace is only declared when used.
+func (w *Writer) collectSheetRels(sh *Sheet) *sheetRels {
+	sr := &sheetRels{rels: map[string]RelInfo{}}
+
+	if len(sheetComments(sh)) > 0 {
+		w.lastCommentsId++
+		sr.commentsN = w.lastCommentsId
+		sr.add(RelInfo{
+			Type:   "http://schemas.openxmlformats.org/officeDocument/2006/relationships/comments",
+			Target: fmt.Sprintf("../comments%d.xml", sr.commentsN),
+		})
+		sr.legacyDrawing = sr.add(RelInfo{
+			Type:   "http://schemas.openxmlformats.org/officeDocument/2006/relationships/vmlDrawing",
+			Target: fmt.Sprintf("../drawings/vmlDrawing%d.vml", sr.commentsN),
+		})
+	}
+
+	for _, r := range sh.Rows {
+		for _, c := range r.Cells {
+			if c.hyperlink == nil || c.hyperlink.URL == "" && c.hyperlink.Location == "" {
+				continue
+			}
+			h := sheetHyperlink{ref: c.coord, link: c.hyperlink}
+			if c.hyperlink.URL != "" {
+				h.rid = sr.add(RelInfo{
+					Type:       "http://schemas.openxmlformats.org/officeDocument/2006/relationships/hyperlink",
+					Target:     c.hyperlink.URL,
+					TargetMode: "External",
+				})
+			}
+			sr.hyperlinks = append(sr.hyperlinks, h)
+		}
+	}
+
+	return sr
+}
+
+func sheetComments(sh *Sheet) []*Cell {
+	var cc []*Cell
+	for _, r := range sh.Rows {
+		for _, c := range r.Cells {
+			if c.comment != nil {
+				cc = append(cc, c)
+			}
+		}
+	}
+	return cc
+}
+
+// writeComments writes the comments part of a sheet along with the vml
+// drawing that holds the comment boxes.
+func (w *Writer) writeComments(sh *Sheet, n int) error {
+	cells := sheetComments(sh)
+
+	abspath := fmt.Sprintf("/xl/comments%d.xml", n)
+	w.PartContentTypes[abspath] = "application/vnd.openxmlformats-officedocument.spreadsheetml.comments+xml"
+
+	var authors []string
+	for _, c := range cells {
+		if !slices.Contains(authors, c.comment.Author) {
+			authors = append(authors, c.comment.Author)
+		}
+	}
+
+	bb := bytes.Buffer{}
+	x := w.newXML(&bb, abspath)
+
+	x.OTag("comments")
+	x.Attr("xmlns", "http://schemas.openxmlformats.org/spreadsheetml/2006/main")
+
+	x.OTag("+authors")
+	for _, a := range authors {
+		x.OTag("+author").String(w.sanitizeText(a)).CTag()
+	}
+	x.CTag() // authors
+
+	x.OTag("+commentList")
+	for _, c := range cells {
+		x.OTag("+comment").Attr("ref", c.coord).Attr("authorId", slices.Index(authors, c.comment.Author))
+		x.OTag("text")
+		x.OTag("t").Attr("xml:space", "preserve").String(w.sanitizeText(c.comment.Text)).CTag()
+		x.CTag() // text
+		x.CTag() // comment
+	}
+	x.CTag() // commentList
+
+	x.CTag() // comments
+
+	err := w.out.WriteBlob(abspath, bb.Bytes())
+	if err != nil {
+		return err
+	}
+
+	return w.writeCommentsVML(sh, cells, n)
+}
+
+// shapesPerBlock is the number of vml shape ids in a block claimed with
+// o:idmap. Block n spans the ids n*1024 to n*1024+1023, but like Excel we
+// never give its first id to a shape (Excel starts at 1025 for block 1),
+// so the shapes get n*1024+1 to n*1024+1023 and a block holds 1023 of
+// them, not 1024.
+const shapesPerBlock = 1023
+
+func (w *Writer) writeCommentsVML(sh *Sheet, cells []*Cell, n int) error {
+	w.DefaultContentTypes["vml"] = "application/vnd.openxmlformats-officedocument.vmlDrawing"
+
+	rowHeights := map[int]float32{}
+	for _, r := range sh.Rows {
+		if r.Height > 0 {
+			rowHeights[r.rowNumber] = r.Height
+		}
+	}
+	colPx := func(col int) int { // 1-based
+		if c := sh.Columns[col]; c != nil && c.Width > 0 {
+			return int(c.Width*7 + 5)
+		}
+		if sh.DefaultColWidth > 0 {
+			return int(sh.DefaultColWidth*7 + 5)
+		}
+		return 64
+	}
+	rowPx := func(row int) int { // 1-based
+		if h, ok := rowHeights[row]; ok {
+			return int(h * 4 / 3)
+		}
+		if sh.DefaultRowHeight > 0 {
+			return int(sh.DefaultRowHeight * 4 / 3)
+		}
+		return 20
+	}
+
+	bb := bytes.Buffer{}
+	vmlpath := fmt.Sprintf("/xl/drawings/vmlDrawing%d.vml", n)
+	x := xml.NewWriter(&bb, w.partXMLConfig(vmlpath))
+
+	x.OTag("xml")
+	x.Attr("xmlns:v", "urn:schemas-microsoft-com:vml")
+	x.Attr("xmlns:o", "urn:schemas-microsoft-com:office:office")
+	x.Attr("xmlns:x", "urn:schemas-microsoft-com:office:excel")
+
+	// shape ids are unique across the drawings of the workbook, each
+	// drawing claims as many blocks of ids as it needs
+	var blocks []string
+	firstBlock := w.lastShapeBlock + 1
+	for range (len(cells) + shapesPerBlock - 1) / shapesPerBlock {
+		w.lastShapeBlock++
+		blocks = append(blocks, strconv.Itoa(w.lastShapeBlock))
+	}
+	x.OTag("+o:shapelayout").Attr("v:ext", "edit")
+	x.OTag("o:idmap").Attr("v:ext", "edit").Attr("data", strings.Join(blocks, ",")).CTag()
+	x.CTag()
+
+	x.OTag("+v:shapetype").Attr("id", "_x0000_t202").Attr("coordsize", "21600,21600")
+	x.Attr("o:spt", 202).Attr("path", "m,l,21600r21600,l21600,xe")
+	x.OTag("v:stroke").Attr("joinstyle", "miter").CTag()
+	x.OTag("v:path").Attr("gradientshapeok", "t").Attr("o:connecttype", "rect").CTag()
+	x.CTag() // shapetype
+
+	for i, c := range cells {
+		width, height := c.comment.Width, c.comment.Height
+		if width <= 0 {
+			width = DefaultCommentWidth
+		}
+		if height <= 0 {
+			height = DefaultCommentHeight
+		}
+
+		// the box starts one column to the right of the cell, slightly
+		// above it; the anchor is expressed as 0-based column/row numbers
+		// with pixel offsets into those
+		col, row := c.columnNumber, c.row.rowNumber
+		left, leftOff := col, 15
+		top, topOff := max(row-2, 0), 10
+		right, rightOff := left, leftOff+width
+		for rightOff > colPx(right+1) {
+			rightOff -= colPx(right + 1)
+			right++
+		}
+		bottom, bottomOff := top, topOff+height
+		for bottomOff > rowPx(bottom+1) {
+			bottomOff -= rowPx(bottom + 1)
+			bottom++
+		}
+
+		id := (firstBlock+i/shapesPerBlock)*1024 + i%shapesPerBlock + 1
+		x.OTag("+v:shape").Attr("id", fmt.Sprintf("_x0000_s%d", id)).Attr("type", "#_x0000_t202")
+		x.Attr("style", fmt.Sprintf("position:absolute;margin-left:0;margin-top:0;width:%dpt;height:%dpt;z-index:%d;visibility:hidden",
+			width*3/4, height*3/4, i+1))
+		x.Attr("fillcolor", "#ffffe1").Attr("o:insetmode", "auto")
+		x.OTag("v:fill").Attr("color2", "#ffffe1").CTag()
+		x.OTag("v:shadow").Attr("on", "t").Attr("color", "black").Attr("obscured", "t").CTag()
+		x.OTag("v:path").Attr("o:connecttype", "none").CTag()
+		x.OTag("v:textbox").Attr("style", "mso-direction-alt:auto")
+		x.OTag("div").Attr("style", "text-align:left").CTag()
+		x.CTag() // textbox
+		x.OTag("x:ClientData").Attr("ObjectType", "Note")
+		x.OTag("x:MoveWithCells").CTag()
+		x.OTag("x:SizeWithCells").CTag()
+		x.OTag("x:Anchor").Write(fmt.Sprintf("%d, %d, %d, %d, %d, %d, %d, %d",
+			left, leftOff, top, topOff, right, rightOff, bottom, bottomOff)).CTag()
+		x.OTag("x:AutoFill").Write("False").CTag()
+		x.OTag("x:Row").Write(row - 1).CTag()
+		x.OTag("x:Column").Write(col - 1).CTag()
+		x.CTag() // ClientData
+		x.CTag() // shape
+	}
+
+	x.CTag() // xml
+
+	return w.out.WriteBlob(vmlpath, bb.Bytes())
+}
+
 func (w *Writer) writeSharedStrings() error {
 	_, rid := w.nextWorkbookID()
 
@@ -538,17 +2322,20 @@ func (w *Writer) writeSharedStrings() error {
 	}
 
 	bb := bytes.Buffer{}
-	x := xml.NewWriter(&bb, xml.WriterConfig{Indent: xml.Indent2Spaces})
-	x.XmlStandaloneDecl()
+	x := w.newXML(&bb, abspath)
 
 	x.OTag("sst")
 	x.Attr("xmlns", "http://schemas.openxmlformats.org/spreadsheetml/2006/main")
-	x.Attr("count", len(w.sharedStrings))
-	x.Attr("uniqueCount", len(w.sharedStrings))
+	if !w.OmitSharedStringCounts {
+		// count is the number of cells that refer to the table, strings
+		// interned without being used do not add to it
+		x.Attr("count", w.sharedStringRefs)
+		x.Attr("uniqueCount", len(w.sharedStrings))
+	}
 
 	for _, s := range w.sharedStrings {
 		x.OTag("+si")
-		x.OTag("t").Write(s).CTag()
+		w.writeText(x, s)
 		x.CTag()
 	}
 
@@ -557,6 +2344,88 @@ func (w *Writer) writeSharedStrings() error {
 	return w.out.WriteBlob(abspath, bb.Bytes())
 }
 
+// writeText writes the <t> element of a shared or inline string. Excel
+// trims leading and trailing whitespace unless it is marked as significant.
+func (w *Writer) writeText(x *xml.Writer, s string) {
+	s = w.sanitizeText(s)
+	x.OTag("t")
+	if s != "" && (isXMLSpace(s[0]) || isXMLSpace(s[len(s)-1])) {
+		x.Attr("xml:space", "preserve")
+	}
+	x.Write(s).CTag()
+}
+
+// sanitizeText prepares a user supplied string for the spreadsheet parts,
+// see EscapeControlChars; all such strings go through it, not only those
+// of cells. Excel decodes _xHHHH_ sequences in any string, so the
+// underscore of such literal text is escaped as well.
+func (w *Writer) sanitizeText(s string) string {
+	return cleanText(s, true, w.EscapeControlChars)
+}
+
+// sanitizePackageText is sanitizeText for the parts outside of the
+// spreadsheet markup, the document properties and relationships, where
+// _xHHHH_ sequences are not decoded: characters that are not allowed in
+// XML are dropped.
+func sanitizePackageText(s string) string {
+	return cleanText(s, false, false)
+}
+
+// cleanText replaces invalid UTF-8 with U+FFFD and drops the characters
+// that are not allowed in XML, or writes them as _xHHHH_ escapes with
+// escapeChars. Literal escape sequences are protected with escapeSeqs.
+func cleanText(s string, escapeSeqs, escapeChars bool) string {
+	clean := true
+	for i := 0; i < len(s) && clean; {
+		r, n := utf8.DecodeRuneInString(s[i:])
+		clean = isXMLChar(r) && !(r == utf8.RuneError && n == 1) && !(escapeSeqs && isEscapeSeq(s[i:]))
+		i += n
+	}
+	if clean {
+		return s
+	}
+
+	var sb strings.Builder
+	for i := 0; i < len(s); {
+		r, n := utf8.DecodeRuneInString(s[i:])
+		switch {
+		case r == utf8.RuneError && n == 1:
+			sb.WriteRune(utf8.RuneError)
+		case escapeSeqs && isEscapeSeq(s[i:]):
+			sb.WriteString("_x005F_")
+		case isXMLChar(r):
+			sb.WriteRune(r)
+		case escapeChars:
+			fmt.Fprintf(&sb, "_x%04X_", r)
+		}
+		i += n
+	}
+	return sb.String()
+}
+
+// isXMLChar reports whether r is allowed in XML 1.0 documents.
+func isXMLChar(r rune) bool {
+	return r == '\t' || r == '\n' || r == '\r' ||
+		r >= 0x20 && r <= 0xd7ff || r >= 0xe000 && r <= 0xfffd || r >= 0x10000 && r <= 0x10ffff
+}
+
+// isEscapeSeq reports whether s starts with an _xHHHH_ escape sequence.
+func isEscapeSeq(s string) bool {
+	if len(s) < 7 || s[0] != '_' || s[1] != 'x' || s[6] != '_' {
+		return false
+	}
+	for i := 2; i < 6; i++ {
+		if !strings.ContainsRune("0123456789ABCDEFabcdef", rune(s[i])) {
+			return false
+		}
+	}
+	return true
+}
+
+func isXMLSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
 func (w *Writer) writeMedia() error {
 	if len(w.media) == 0 {
 		return nil
@@ -589,8 +2458,7 @@ func (w *Writer) writeMetadata() error {
 	}
 
 	bb := bytes.Buffer{}
-	x := xml.NewWriter(&bb, xml.WriterConfig{Indent: xml.Indent2Spaces})
-	x.XmlStandaloneDecl()
+	x := w.newXML(&bb, abspath)
 
 	x.OTag("metadata")
 	x.Attr("xmlns", "http://schemas.openxmlformats.org/spreadsheetml/2006/main")
@@ -608,22 +2476,25 @@ func (w *Writer) writeMetadata() error {
 	x.CTag() // metadataType
 	x.CTag() // metadataTypes
 
-	x.OTag("futureMetadata").Attr("name", "XLRICHVALUE").Attr("count", len(w.media))
-	for _, m := range w.media {
+	// futureMetadata and valueMetadata are emitted in the same order, so the
+	// i-th valueMetadata entry (vm=i+1) points to the i-th futureMetadata
+	// block, which in turn points to the rich value
+	x.OTag("futureMetadata").Attr("name", "XLRICHVALUE").Attr("count", len(w.valueMetadata))
+	for _, m := range w.valueMetadata {
 		x.OTag("+bk")
 		x.OTag("extLst")
 		x.OTag("ext").Attr("uri", "{3e2802c4-a4d2-4d8b-9148-e3be6c30e623}")
-		x.OTag("xlrd:rvb").Attr("i", m.IId).CTag()
+		x.OTag("xlrd:rvb").Attr("i", m.richValue).CTag()
 		x.CTag() // ext
 		x.CTag() // extLst
 		x.CTag() // bk
 	}
 	x.CTag() // futureMetadata
 
-	x.OTag("valueMetadata").Attr("count", len(w.media))
-	for _, m := range w.media {
+	x.OTag("valueMetadata").Attr("count", len(w.valueMetadata))
+	for i := range w.valueMetadata {
 		x.OTag("+bk")
-		x.OTag("rc").Attr("t", 1).Attr("v", m.IId).CTag()
+		x.OTag("rc").Attr("t", 1).Attr("v", i).CTag()
 		x.CTag() // bk
 	}
 	x.CTag() // valueMetadata
@@ -646,8 +2517,7 @@ func (w *Writer) writeRichValueRel() error {
 	}
 
 	bb := bytes.Buffer{}
-	x := xml.NewWriter(&bb, xml.WriterConfig{Indent: xml.Indent2Spaces})
-	x.XmlStandaloneDecl()
+	x := w.newXML(&bb, abspath)
 
 	x.OTag("richValueRels")
 	x.Attr("xmlns", "http://schemas.microsoft.com/office/spreadsheetml/2022/richvaluerel")
@@ -677,8 +2547,7 @@ func (w *Writer) writeRichValueStructure() error {
 	}
 
 	bb := bytes.Buffer{}
-	x := xml.NewWriter(&bb, xml.WriterConfig{Indent: xml.Indent2Spaces})
-	x.XmlStandaloneDecl()
+	x := w.newXML(&bb, abspath)
 
 	x.OTag("rvStructures")
 	x.Attr("xmlns", "http://schemas.microsoft.com/office/spreadsheetml/2017/richdata")
@@ -708,8 +2577,7 @@ func (w *Writer) writeRichValueData() error {
 	}
 
 	bb := bytes.Buffer{}
-	x := xml.NewWriter(&bb, xml.WriterConfig{Indent: xml.Indent2Spaces})
-	x.XmlStandaloneDecl()
+	x := w.newXML(&bb, abspath)
 
 	x.OTag("rvData")
 
@@ -741,8 +2609,7 @@ func (w *Writer) writeRichValueTypes() error {
 	}
 
 	bb := bytes.Buffer{}
-	x := xml.NewWriter(&bb, xml.WriterConfig{Indent: xml.Indent2Spaces})
-	x.XmlStandaloneDecl()
+	x := w.newXML(&bb, abspath)
 
 	x.OTag("rvTypesInfo")
 	x.Attr("xmlns", "http://schemas.microsoft.com/office/spreadsheetml/2017/richdata2")
@@ -775,13 +2642,13 @@ func (w *Writer) writeRichValueTypes() error {
 
 func (w *Writer) writeRels(path string, rels map[string]RelInfo) error {
 	bb := bytes.Buffer{}
-	x := xml.NewWriter(&bb, xml.WriterConfig{Indent: xml.Indent2Spaces})
-	x.XmlStandaloneDecl()
+	x := w.newXML(&bb, path)
 
 	x.OTag("Relationships")
 	x.Attr("xmlns", "http://schemas.openxmlformats.org/package/2006/relationships")
-	err := enumerate(rels, func(rid string, info RelInfo) error {
-		x.OTag("+Relationship").Attr("Id", rid).Attr("Type", info.Type).Attr("Target", info.Target)
+	err := enumerateFunc(rels, compareRelIDs, func(rid string, info RelInfo) error {
+		x.OTag("+Relationship").Attr("Id", rid).Attr("Type", info.Type).Attr("Target", sanitizePackageText(info.Target))
+		x.OptStringAttr("TargetMode", info.TargetMode)
 		x.CTag()
 
 		return nil
@@ -794,6 +2661,36 @@ func (w *Writer) writeRels(path string, rels map[string]RelInfo) error {
 	return w.out.WriteBlob(path, bb.Bytes())
 }
 
+// enumerateFunc is like enumerate with a custom key order.
+func enumerateFunc[M ~map[K]V, K comparable, V any](m M, cmp func(a, b K) int, callback func(k K, v V) error) error {
+	keys := maps.Keys(m)
+	slices.SortFunc(keys, cmp)
+	for _, k := range keys {
+		err := callback(k, m[k])
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// relID formats the n-th relationship id of a part. All ids are allocated
+// through it, which keeps them in the form expected by compareRelIDs.
+func relID(n int) string {
+	return "rId" + strconv.Itoa(n)
+}
+
+// compareRelIDs orders relationship ids numerically, so that "rId2" comes
+// before "rId10". Ids without a numeric suffix sort lexically.
+func compareRelIDs(a, b string) int {
+	na, erra := strconv.Atoi(strings.TrimPrefix(a, "rId"))
+	nb, errb := strconv.Atoi(strings.TrimPrefix(b, "rId"))
+	if erra != nil || errb != nil {
+		return strings.Compare(a, b)
+	}
+	return na - nb
+}
+
 func enumerate[M ~map[K]V, K constraints.Ordered, V any](m M, callback func(k K, v V) error) error {
 	keys := maps.Keys(m)
 	slices.Sort(keys)
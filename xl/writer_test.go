@@ -0,0 +1,205 @@
+package xl
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestControlCharacters checks that the control characters of user
+// supplied strings never reach the parts, where even as character
+// references they are not allowed by XML 1.0.
+func TestControlCharacters(t *testing.T) {
+	const s = "line1\x00line2"
+	wb, sh := newTestSheet(t, "Sheet1")
+	wb.AppName = s
+	wb.Company = s
+	wb.CoreProperties.Title = s
+	if err := wb.SetCustomProperty(s, s); err != nil {
+		t.Fatal(err)
+	}
+	mustCell(t, sh, "A1").SetStr(s)
+	mustCell(t, sh, "A2").SetInlineStr(s)
+	mustCell(t, sh, "A3").SetComment(s, s)
+	c := mustCell(t, sh, "A4")
+	c.SetHyperlink("https://example.com/"+s, s)
+	c.Hyperlink().Tooltip = s
+	sh.HeaderFooter.OddHeader = s
+	if err := sh.AddDataValidation("B1", DataValidation{
+		Type: "list", Formula1: `"a,b"`,
+		PromptTitle: s, Prompt: s, ErrorTitle: s, Error: s,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	m := writeParts(t, wb)
+	for name, b := range m {
+		if strings.ContainsRune(string(b), 0) || strings.Contains(string(b), "&#0;") ||
+			strings.Contains(strings.ToLower(string(b)), "&#x0;") {
+			t.Errorf("%s: control character written", name)
+		}
+	}
+	wantContains(t, m.part(t, "/xl/sharedStrings.xml"), "<t>line1line2</t>")
+	wantContains(t, m.part(t, "/xl/worksheets/sheet1.xml"),
+		`<is><t>line1line2</t></is>`,
+		`<oddHeader>line1line2</oddHeader>`,
+		`tooltip="line1line2"`,
+		`prompt="line1line2"`)
+	wantContains(t, m.part(t, "/docProps/core.xml"), "<dc:title>line1line2</dc:title>")
+	wantContains(t, m.part(t, "/docProps/custom.xml"), `name="line1line2"`)
+}
+
+func TestEscapeControlCharacters(t *testing.T) {
+	wb, sh := newTestSheet(t, "Sheet1")
+	mustCell(t, sh, "A1").SetInlineStr("line1\x00line2 _x0041_")
+	mustCell(t, sh, "A2").SetComment("me", "a\x01b")
+	m := memStorage{}
+	w := NewWriter(m)
+	w.EscapeControlChars = true
+	if err := w.Write(wb); err != nil {
+		t.Fatal(err)
+	}
+	wantContains(t, m.part(t, "/xl/worksheets/sheet1.xml"), "<t>line1_x0000_line2 _x005F_x0041_</t>")
+	wantContains(t, m.part(t, "/xl/comments1.xml"), ">a_x0001_b</t>")
+}
+
+func TestSharedIndex(t *testing.T) {
+	wb, sh := newTestSheet(t, "Data")
+	m := memStorage{}
+	w := NewWriter(m)
+	ii := w.InternStrings([]string{"red", "green"})
+	mustCell(t, sh, "A1").SetSharedIndex(ii[1])
+	mustCell(t, sh, "A2").SetStr("blue")
+	if err := w.Write(wb); err != nil {
+		t.Fatal(err)
+	}
+	wantContains(t, m.part(t, "/xl/worksheets/sheet1.xml"),
+		`<c r="A1" t="s"><v>1</v></c>`, `<c r="A2" t="s"><v>2</v></c>`)
+
+	// index 2 would be "blue", but only the interned strings count
+	wb, sh = newTestSheet(t, "Data")
+	m = memStorage{}
+	w = NewWriter(m)
+	w.InternStrings([]string{"red", "green"})
+	mustCell(t, sh, "A1").SetStr("blue")
+	mustCell(t, sh, "A2").SetSharedIndex(2)
+	err := w.Write(wb)
+	if err == nil || !strings.Contains(err.Error(), "cell A2: shared string index 2 is out of range") {
+		t.Errorf("got %v, want an out of range error", err)
+	}
+	if len(m) != 0 {
+		t.Errorf("%d parts written before the error", len(m))
+	}
+}
+
+func TestRawData(t *testing.T) {
+	wb, sh := newTestSheet(t, "Data")
+	sh.RawData = true
+	sh.DefaultStyle = XF{Font: Font{Bold: true}}
+	sh.SetColumnStyle(1, XF{NumFmt: "0.00"})
+	c := mustCell(t, sh, "A1")
+	c.SetFloat(1.5)
+	c.XF = XF{Fill: Fill{PatternType: "solid", FgColor: "FF0000"}}
+	mustCell(t, sh, "B1").SetStr("x")
+	m := writeParts(t, wb)
+	if _, ok := m["/xl/styles.xml"]; ok {
+		t.Error("styles part written in raw data mode")
+	}
+	s := m.part(t, "/xl/worksheets/sheet1.xml")
+	wantContains(t, s, `<col min="1" max="1"/>`, `<c r="A1" t="n"><v>1.5</v></c>`, `<c r="B1" t="s"><v>0</v></c>`)
+	if strings.Contains(s, ` s="`) || strings.Contains(s, ` style="`) {
+		t.Errorf("styles referenced in raw data mode:\n%s", s)
+	}
+}
+
+func benchmarkWrite(b *testing.B, raw bool) {
+	wb := NewWorkbook()
+	sh, err := wb.AddSheet("Data")
+	if err != nil {
+		b.Fatal(err)
+	}
+	sh.RawData = raw
+	for i := 0; i < 10000; i++ {
+		r := sh.AddRow()
+		r.AddCell().SetInt(int64(i))
+		r.AddCell().SetFloat(float64(i) / 7)
+		c := r.AddCell()
+		c.SetStr("item")
+		c.XF = XF{Font: Font{Bold: i%2 == 0}}
+	}
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		if err := NewWriter(memStorage{}).Write(wb); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkWriteStyled(b *testing.B)  { benchmarkWrite(b, false) }
+func BenchmarkWriteRawData(b *testing.B) { benchmarkWrite(b, true) }
+
+func TestPreserveSpace(t *testing.T) {
+	wb, sh := newTestSheet(t, "Data")
+	values := []string{"  x  ", "x", "a b", "\tx", "x\n", "   "}
+	for i, s := range values {
+		mustCell(t, sh, CellCoordAsString(1, i+1)).SetStr(s)
+		mustCell(t, sh, CellCoordAsString(2, i+1)).SetInlineStr(s)
+	}
+	m := writeParts(t, wb)
+	sst := m.part(t, "/xl/sharedStrings.xml")
+	wantContains(t, sst,
+		`<si><t xml:space="preserve">  x  </t></si>`,
+		`<si><t>x</t></si>`,
+		`<si><t>a b</t></si>`,
+		"<si><t xml:space=\"preserve\">\tx</t></si>",
+		`<si><t xml:space="preserve">   </t></si>`)
+	wantContains(t, m.part(t, "/xl/worksheets/sheet1.xml"),
+		`<c r="B1" t="inlineStr"><is><t xml:space="preserve">  x  </t></is></c>`,
+		`<c r="B2" t="inlineStr"><is><t>x</t></is></c>`)
+
+	// the spaces survive a round trip
+	rwb := roundTrip(t, wb)
+	for i, r := range rwb.Sheets[0].Rows {
+		for _, c := range r.Cells {
+			if c.Value() != values[i] {
+				t.Errorf("%s: got %q, want %q", c.coord, c.Value(), values[i])
+			}
+		}
+	}
+}
+
+func TestInlineStrings(t *testing.T) {
+	wb, sh := newTestSheet(t, "Data")
+	mustCell(t, sh, "A1").SetStr("shared")
+	mustCell(t, sh, "A2").SetStr("shared")
+	mustCell(t, sh, "A3").SetInlineStr("inline")
+
+	m := memStorage{}
+	w := NewWriter(m)
+	w.InlineStrings = true
+	if err := w.Write(wb); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := m["/xl/sharedStrings.xml"]; ok {
+		t.Error("sharedStrings.xml written with InlineStrings")
+	}
+	if s := m.part(t, "/xl/_rels/workbook.xml.rels"); strings.Contains(s, "sharedStrings") {
+		t.Error("shared strings relationship written with InlineStrings")
+	}
+	if s := m.part(t, "[Content_Types].xml"); strings.Contains(s, "sharedStrings") {
+		t.Error("shared strings content type written with InlineStrings")
+	}
+	s := m.part(t, "/xl/worksheets/sheet1.xml")
+	wantContains(t, s,
+		`<c r="A1" t="inlineStr"><is><t>shared</t></is></c>`,
+		`<c r="A2" t="inlineStr"><is><t>shared</t></is></c>`,
+		`<c r="A3" t="inlineStr"><is><t>inline</t></is></c>`)
+	if strings.Contains(s, `t="s"`) {
+		t.Error("shared string reference written with InlineStrings")
+	}
+
+	// by default, only SetInlineStr is inline
+	m = writeParts(t, wb)
+	wantContains(t, m.part(t, "/xl/sharedStrings.xml"), `<si><t>shared</t></si>`)
+	wantContains(t, m.part(t, "/xl/worksheets/sheet1.xml"),
+		`<c r="A1" t="s"><v>0</v></c>`, `<c r="A2" t="s"><v>0</v></c>`, `<c r="A3" t="inlineStr">`)
+}
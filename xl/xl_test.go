@@ -0,0 +1,119 @@
+package xl
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+// memStorage keeps the parts of a written package in memory, by absolute
+// part name.
+type memStorage map[string][]byte
+
+func (m memStorage) WriteBlob(path string, blob []byte) error {
+	m[path] = append([]byte(nil), blob...)
+	return nil
+}
+
+// part returns the content of a part, failing the test if it is missing.
+func (m memStorage) part(t *testing.T, name string) string {
+	t.Helper()
+	b, ok := m[name]
+	if !ok {
+		t.Fatalf("missing part %s", name)
+	}
+	return string(b)
+}
+
+// writeParts writes the workbook with the default settings and checks
+// that every XML part is well-formed.
+func writeParts(t *testing.T, wb *Workbook) memStorage {
+	t.Helper()
+	m := memStorage{}
+	if err := NewWriter(m).Write(wb); err != nil {
+		t.Fatal(err)
+	}
+	for name, b := range m {
+		if !strings.HasSuffix(name, ".xml") && !strings.HasSuffix(name, ".rels") && !strings.HasSuffix(name, ".vml") {
+			continue
+		}
+		d := xml.NewDecoder(strings.NewReader(string(b)))
+		for {
+			_, err := d.Token()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+		}
+	}
+	return m
+}
+
+// roundTrip writes the workbook as a zip package and reads it back.
+func roundTrip(t *testing.T, wb *Workbook) *Workbook {
+	t.Helper()
+	var bb bytes.Buffer
+	zs := NewZipStorage(&bb)
+	if err := NewWriter(zs).Write(wb); err != nil {
+		t.Fatal(err)
+	}
+	if err := zs.Close(); err != nil {
+		t.Fatal(err)
+	}
+	rwb, err := Read(bytes.NewReader(bb.Bytes()), int64(bb.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rwb
+}
+
+// mustCell returns the cell at an A1 reference.
+func mustCell(t *testing.T, sh *Sheet, ref string) *Cell {
+	t.Helper()
+	c, err := sh.Cell(ref)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+// newTestSheet returns a new workbook with a single sheet.
+func newTestSheet(t *testing.T, name string) (*Workbook, *Sheet) {
+	t.Helper()
+	wb := NewWorkbook()
+	sh, err := wb.AddSheet(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return wb, sh
+}
+
+// newTestSheets returns a new workbook with a sheet for each name, the
+// name is stored in cell A1 of its sheet.
+func newTestSheets(t *testing.T, names ...string) *Workbook {
+	t.Helper()
+	wb := NewWorkbook()
+	for _, name := range names {
+		sh, err := wb.AddSheet(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		mustCell(t, sh, "A1").SetStr(name)
+	}
+	return wb
+}
+
+// wantContains fails the test unless s contains each of the substrings.
+func wantContains(t *testing.T, s string, subs ...string) {
+	t.Helper()
+	for _, sub := range subs {
+		if !strings.Contains(s, sub) {
+			t.Errorf("missing %q in\n%s", sub, s)
+		}
+	}
+}
@@ -2,6 +2,8 @@ package xl
 
 import (
 	"archive/zip"
+	"compress/flate"
+	"fmt"
 	"io"
 	"os"
 	"path/filepath"
@@ -17,7 +19,8 @@ type DirStorage struct {
 }
 
 type ZipStorage struct {
-	z *zip.Writer
+	z      *zip.Writer
+	method uint16
 }
 
 func NewDirStorage(dir string) *DirStorage {
@@ -37,12 +40,33 @@ func (ds *DirStorage) WriteBlob(path string, blob []byte) error {
 }
 
 func NewZipStorage(out io.Writer) *ZipStorage {
-	return &ZipStorage{z: zip.NewWriter(out)}
+	return &ZipStorage{z: zip.NewWriter(out), method: zip.Deflate}
+}
+
+// NewZipStorageLevel is NewZipStorage with a deflate compression level from
+// flate.HuffmanOnly to flate.BestCompression. Higher levels make smaller
+// files at the cost of speed, the XML parts of a workbook compress well.
+// flate.NoCompression stores the parts as they are, which is the fastest
+// but makes the file several times larger. flate.DefaultCompression
+// selects the same level as NewZipStorage.
+func NewZipStorageLevel(out io.Writer, level int) (*ZipStorage, error) {
+	if level < flate.HuffmanOnly || level > flate.BestCompression {
+		return nil, fmt.Errorf("invalid compression level %d", level)
+	}
+	zs := NewZipStorage(out)
+	if level == flate.NoCompression {
+		zs.method = zip.Store
+		return zs, nil
+	}
+	zs.z.RegisterCompressor(zip.Deflate, func(w io.Writer) (io.WriteCloser, error) {
+		return flate.NewWriter(w, level)
+	})
+	return zs, nil
 }
 
 func (zs *ZipStorage) WriteBlob(path string, blob []byte) error {
 	path = strings.TrimPrefix(path, "/")
-	f, err := zs.z.Create(path)
+	f, err := zs.z.CreateHeader(&zip.FileHeader{Name: path, Method: zs.method})
 	if err != nil {
 		return err
 	}
@@ -50,6 +74,47 @@ func (zs *ZipStorage) WriteBlob(path string, blob []byte) error {
 	return err
 }
 
-func (zs *ZipStorage) Close() {
-	zs.z.Close()
+// Close finishes the archive, which is not a valid .xlsx file until then.
+func (zs *ZipStorage) Close() error {
+	return zs.z.Close()
+}
+
+// WriteTo writes the workbook to out as an .xlsx file with the default
+// Writer settings, finishing the archive, e.g. to serve it over HTTP.
+func (wb *Workbook) WriteTo(out io.Writer) (int64, error) {
+	cw := &countingWriter{w: out}
+	zs := NewZipStorage(cw)
+	if err := NewWriter(zs).Write(wb); err != nil {
+		return cw.n, err
+	}
+	err := zs.Close()
+	return cw.n, err
+}
+
+// Save writes the workbook to an .xlsx file, see WriteTo. The file is
+// removed if writing fails.
+func (wb *Workbook) Save(name string) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = wb.WriteTo(f)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(name)
+	}
+	return err
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
 }
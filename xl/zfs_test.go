@@ -0,0 +1,49 @@
+package xl
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/flate"
+	"testing"
+)
+
+func TestZipStorageLevel(t *testing.T) {
+	for _, level := range []int{flate.HuffmanOnly - 1, flate.BestCompression + 1} {
+		if _, err := NewZipStorageLevel(&bytes.Buffer{}, level); err == nil {
+			t.Errorf("accepted level %d", level)
+		}
+	}
+
+	wb, sh := newTestSheet(t, "Sheet1")
+	mustCell(t, sh, "A1").SetStr("hello")
+	for _, tc := range []struct {
+		level  int
+		method uint16
+	}{
+		{flate.NoCompression, zip.Store},
+		{flate.BestSpeed, zip.Deflate},
+		{flate.DefaultCompression, zip.Deflate},
+		{flate.BestCompression, zip.Deflate},
+	} {
+		var buf bytes.Buffer
+		zs, err := NewZipStorageLevel(&buf, tc.level)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = NewWriter(zs).Write(wb); err != nil {
+			t.Fatal(err)
+		}
+		if err = zs.Close(); err != nil {
+			t.Fatal(err)
+		}
+		z, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, f := range z.File {
+			if f.Method != tc.method {
+				t.Errorf("level %d: %s has method %d, want %d", tc.level, f.Name, f.Method, tc.method)
+			}
+		}
+	}
+}